@@ -0,0 +1,275 @@
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	AccountSignerKey      = "key"
+	AccountSignerKeystore = "keystore"
+	AccountSignerClef     = "clef"
+)
+
+// UnsignedTransaction is a contract call priced with the fees.
+type UnsignedTransaction struct {
+	Nonce uint64
+	To    common.Address
+	Gas   uint64
+	Fees  *TransactionFees
+	Data  []byte
+}
+
+// AccountSigner signs the transactions of one account, keeping the key material to itself.
+type AccountSigner interface {
+	Address() common.Address
+	// SignTransaction returns the raw signed transaction and its hash.
+	SignTransaction(chainID *big.Int, transaction UnsignedTransaction) ([]byte, common.Hash, error)
+}
+
+// AccountSignerConfig selects the signer backend. Passphrases of keystores are read from a file or an environment variable.
+type AccountSignerConfig struct {
+	Type           string      `mapstructure:"type"`
+	PrivateKey     string      `mapstructure:"privateKey"`
+	Keystore       string      `mapstructure:"keystore"`
+	PassphraseFile string      `mapstructure:"passphraseFile"`
+	PassphraseEnv  string      `mapstructure:"passphraseEnv"`
+	Endpoint       RPCEndpoint `mapstructure:"endpoint"`
+	Address        string      `mapstructure:"address"`
+}
+
+func MakeAccountSigner(config AccountSignerConfig) (AccountSigner, error) {
+	switch config.Type {
+	case AccountSignerKey:
+		return MakeRawKeySigner(config.PrivateKey)
+	case AccountSignerKeystore:
+		passphrase, err := readPassphrase(config.PassphraseFile, config.PassphraseEnv)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return MakeKeystoreSigner(config.Keystore, passphrase)
+	case AccountSignerClef:
+		return MakeExternalSigner(config.Endpoint, config.Address)
+	}
+
+	return nil, fmt.Errorf("unknown signer type '%s'", config.Type)
+}
+
+func readPassphrase(passphraseFile string, passphraseEnv string) (string, error) {
+	if passphraseFile != "" {
+		passphrase, err := ioutil.ReadFile(passphraseFile)
+
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimRight(string(passphrase), "\r\n"), nil
+	}
+
+	if passphraseEnv != "" {
+		if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+			return passphrase, nil
+		}
+
+		return "", fmt.Errorf("environment variable %s is not set", passphraseEnv)
+	}
+
+	return "", errors.New("keystore passphrase file or environment variable must be set")
+}
+
+// KeySigner signs with a key held in memory, decrypted from a keystore or, for development, a raw one.
+type KeySigner struct {
+	privateKey *ecdsa.PrivateKey
+}
+
+func MakeRawKeySigner(privateKey string) (*KeySigner, error) {
+	key, err := crypto.HexToECDSA(privateKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySigner{key}, nil
+}
+
+// MakeKeystoreSigner decrypts the JSON keystore file, e.g. one made by 'geth account new'.
+func MakeKeystoreSigner(keystorePath string, passphrase string) (*KeySigner, error) {
+	keyJSON, err := ioutil.ReadFile(keystorePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySigner{key.PrivateKey}, nil
+}
+
+func (signer KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(signer.privateKey.PublicKey)
+}
+
+// SignLegacyTransaction signs with the replay protected EIP-155 signer.
+func (signer KeySigner) SignLegacyTransaction(chainID *big.Int, transaction *types.Transaction) (*types.Transaction, error) {
+	return types.SignTx(transaction, types.NewEIP155Signer(chainID), signer.privateKey)
+}
+
+func (signer KeySigner) SignDynamicFeeTransaction(chainID *big.Int, transaction DynamicFeeTransaction) (*DynamicFeeTransaction, error) {
+	transaction.ChainID = chainID
+
+	hash, err := transaction.SigningHash()
+
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := crypto.Sign(hash.Bytes(), signer.privateKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction.WithSignature(signature), nil
+}
+
+func (signer KeySigner) SignTransaction(chainID *big.Int, transaction UnsignedTransaction) ([]byte, common.Hash, error) {
+	if transaction.Fees.Type == DynamicFeeTransactionType {
+		signedTransaction, err := signer.SignDynamicFeeTransaction(chainID, DynamicFeeTransaction{
+			Nonce:                transaction.Nonce,
+			MaxPriorityFeePerGas: transaction.Fees.MaxPriorityFeePerGas,
+			MaxFeePerGas:         transaction.Fees.MaxFeePerGas,
+			Gas:                  transaction.Gas,
+			To:                   transaction.To,
+			Value:                big.NewInt(0),
+			Data:                 transaction.Data,
+		})
+
+		if err != nil {
+			return nil, common.Hash{}, err
+		}
+
+		rawTransaction, err := signedTransaction.MarshalBinary()
+
+		if err != nil {
+			return nil, common.Hash{}, err
+		}
+
+		hash, err := signedTransaction.Hash()
+
+		return rawTransaction, hash, err
+	}
+
+	signedTransaction, err := signer.SignLegacyTransaction(chainID, types.NewTransaction(
+		transaction.Nonce,
+		transaction.To,
+		big.NewInt(0),
+		transaction.Gas,
+		transaction.Fees.GasPrice,
+		transaction.Data,
+	))
+
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	return types.Transactions{signedTransaction}.GetRlp(0), signedTransaction.Hash(), nil
+}
+
+// ExternalSigner asks a Clef compatible signer to sign over HTTP or IPC, so the key never enters the service.
+type ExternalSigner struct {
+	InfuraController
+	address common.Address
+}
+
+func MakeExternalSigner(endpoint RPCEndpoint, address string) (*ExternalSigner, error) {
+	if !common.IsHexAddress(address) {
+		return nil, errors.New("external signer account address is invalid")
+	}
+
+	controller, err := MakeRPCController([]RPCEndpoint{endpoint})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExternalSigner{controller, common.HexToAddress(address)}, nil
+}
+
+func (signer ExternalSigner) Address() common.Address {
+	return signer.address
+}
+
+type externalSignerTransaction struct {
+	From                 string         `json:"from"`
+	To                   string         `json:"to"`
+	Gas                  hexutil.Uint64 `json:"gas"`
+	GasPrice             *hexutil.Big   `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big   `json:"value"`
+	Nonce                hexutil.Uint64 `json:"nonce"`
+	Data                 hexutil.Bytes  `json:"data"`
+	ChainID              *hexutil.Big   `json:"chainId"`
+}
+
+// SignTransaction calls account_signTransaction, which the signer may ask its operator or its rules to approve.
+func (signer ExternalSigner) SignTransaction(chainID *big.Int, transaction UnsignedTransaction) ([]byte, common.Hash, error) {
+	arguments := externalSignerTransaction{
+		From:    signer.address.Hex(),
+		To:      transaction.To.Hex(),
+		Gas:     hexutil.Uint64(transaction.Gas),
+		Value:   (*hexutil.Big)(big.NewInt(0)),
+		Nonce:   hexutil.Uint64(transaction.Nonce),
+		Data:    transaction.Data,
+		ChainID: (*hexutil.Big)(chainID),
+	}
+
+	if transaction.Fees.Type == DynamicFeeTransactionType {
+		arguments.MaxFeePerGas = (*hexutil.Big)(transaction.Fees.MaxFeePerGas)
+		arguments.MaxPriorityFeePerGas = (*hexutil.Big)(transaction.Fees.MaxPriorityFeePerGas)
+	} else {
+		arguments.GasPrice = (*hexutil.Big)(transaction.Fees.GasPrice)
+	}
+
+	result, err := signer.InfuraController.callInfuraRaw(makeRequestPayload(
+		"account_signTransaction",
+		[]interface{}{arguments},
+	))
+
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	var signed struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+
+	if err := json.Unmarshal(result, &signed); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	if len(signed.Raw) == 0 {
+		return nil, common.Hash{}, errors.New("external signer returned no transaction")
+	}
+
+	// Both legacy and typed transactions are hashed as they are sent.
+	return signed.Raw, crypto.Keccak256Hash(signed.Raw), nil
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func testKeySigner() *KeySigner {
+	signer, _ := MakeRawKeySigner("9df9993fcb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
+
+	return signer
+}
+
+func TestMakeRawKeySigner(t *testing.T) {
+	signer, err := MakeRawKeySigner("9df9cb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
+
+	assert.Error(t, err)
+	assert.Nil(t, signer)
+}
+
+func TestMakeAccountSigner_Keystore(t *testing.T) {
+	directory, err := ioutil.TempDir("", "keystore")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(directory)
+
+	account, err := keystore.StoreKey(directory, "secret", keystore.LightScryptN, keystore.LightScryptP)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	passphraseFile := filepath.Join(directory, "passphrase")
+	ioutil.WriteFile(passphraseFile, []byte("secret\n"), 0600)
+
+	signer, err := MakeAccountSigner(AccountSignerConfig{
+		Type:           AccountSignerKeystore,
+		Keystore:       account.URL.Path,
+		PassphraseFile: passphraseFile,
+	})
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, account.Address, signer.Address())
+	}
+
+	os.Setenv("TEST_KEYSTORE_PASSPHRASE", "wrong")
+	defer os.Unsetenv("TEST_KEYSTORE_PASSPHRASE")
+
+	_, err = MakeAccountSigner(AccountSignerConfig{
+		Type:          AccountSignerKeystore,
+		Keystore:      account.URL.Path,
+		PassphraseEnv: "TEST_KEYSTORE_PASSPHRASE",
+	})
+
+	assert.Equal(t, keystore.ErrDecrypt, err)
+
+	_, err = MakeAccountSigner(AccountSignerConfig{Type: AccountSignerKeystore, Keystore: account.URL.Path, PassphraseEnv: "TEST_UNSET_PASSPHRASE"})
+
+	assert.EqualError(t, err, "environment variable TEST_UNSET_PASSPHRASE is not set")
+
+	_, err = MakeAccountSigner(AccountSignerConfig{Type: AccountSignerKeystore, Keystore: account.URL.Path})
+
+	assert.EqualError(t, err, "keystore passphrase file or environment variable must be set")
+
+	_, err = MakeAccountSigner(AccountSignerConfig{Type: "ledger"})
+
+	assert.EqualError(t, err, "unknown signer type 'ledger'")
+}
+
+func TestExternalSigner_SignTransaction(t *testing.T) {
+	keySigner := testKeySigner()
+
+	signer, err := MakeAccountSigner(AccountSignerConfig{
+		Type:     AccountSignerClef,
+		Endpoint: RPCEndpoint{URL: "http://clef.test/"},
+		Address:  keySigner.Address().Hex(),
+	})
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// The fake signer signs what it's asked to with the key of the account.
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		"http://clef.test/",
+		func(request *http.Request) (*http.Response, error) {
+			requestBody := new(struct {
+				Method string                      `json:"method"`
+				Params []externalSignerTransaction `json:"params"`
+			})
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return nil, err
+			}
+
+			arguments := requestBody.Params[0]
+
+			if requestBody.Method != "account_signTransaction" || arguments.From != keySigner.Address().Hex() {
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "Request denied"}}`), nil
+			}
+
+			rawTransaction, _, err := keySigner.SignTransaction(arguments.ChainID.ToInt(), UnsignedTransaction{
+				Nonce: uint64(arguments.Nonce),
+				To:    common.HexToAddress(arguments.To),
+				Gas:   uint64(arguments.Gas),
+				Fees: &TransactionFees{
+					Type:                 DynamicFeeTransactionType,
+					MaxFeePerGas:         arguments.MaxFeePerGas.ToInt(),
+					MaxPriorityFeePerGas: arguments.MaxPriorityFeePerGas.ToInt(),
+				},
+				Data: arguments.Data,
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			return httpmock.NewJsonResponse(http.StatusOK, map[string]interface{}{
+				"jsonrpc": "2.0",
+				"result":  map[string]interface{}{"raw": hexutil.Bytes(rawTransaction)},
+			})
+		},
+	)
+
+	transaction := UnsignedTransaction{
+		Nonce: 7,
+		To:    common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Gas:   210000,
+		Fees:  &TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(30000000000), MaxPriorityFeePerGas: big.NewInt(1500000000)},
+		Data:  []byte{1, 2, 3},
+	}
+
+	rawTransaction, hash, err := signer.SignTransaction(big.NewInt(5), transaction)
+
+	if assert.NoError(t, err) {
+		expectedRawTransaction, expectedHash, _ := keySigner.SignTransaction(big.NewInt(5), transaction)
+
+		assert.Equal(t, expectedRawTransaction, rawTransaction)
+		assert.Equal(t, expectedHash, hash)
+		assert.Equal(t, crypto.Keccak256Hash(rawTransaction), hash)
+	}
+
+	_, err = MakeAccountSigner(AccountSignerConfig{Type: AccountSignerClef, Endpoint: RPCEndpoint{URL: "http://clef.test/"}})
+
+	assert.EqualError(t, err, "external signer account address is invalid")
+
+	denyingSigner, _ := MakeExternalSigner(RPCEndpoint{URL: "http://clef.test/"}, common.HexToAddress("0x123").Hex())
+
+	_, _, err = denyingSigner.SignTransaction(big.NewInt(5), transaction)
+
+	assert.EqualError(t, err, "Request denied")
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"MCW-btc-module/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+)
+
+// BalanceMonitorController checks that the signing accounts can pay for the pending mints. It pauses the signer pool
+// while none of the accounts can pay for a single mint, so that purchases wait for balance rather than fail, and
+// alerts the operator whenever the balances run low or minting pauses or resumes.
+type BalanceMonitorController struct {
+	signerPool    *SignerPool
+	feeController FeeController
+	notifier      Notifier
+	database      *gorm.DB
+	// mintGasLimit is used until a mint has been sent, then the gas limit estimated for the latest mint.
+	mintGasLimit uint64
+	// reserveMints is the number of mints the balances must cover on top of the pending ones.
+	reserveMints int64
+	isLow        bool
+}
+
+// BalanceReport is the outcome of a balance check.
+type BalanceReport struct {
+	MintCost     *big.Int
+	PendingMints int64
+	// AffordableMints is the number of mints the accounts can pay for, each paying for whole mints only.
+	AffordableMints int64
+	Balances        []AccountBalance
+}
+
+type AccountBalance struct {
+	Address common.Address
+	Balance *big.Int
+}
+
+func MakeBalanceMonitorController(
+	signerPool *SignerPool,
+	feeController FeeController,
+	notifier Notifier,
+	database *gorm.DB,
+	mintGasLimit uint64,
+	reserveMints int64,
+) (*BalanceMonitorController, error) {
+	if mintGasLimit == 0 {
+		return nil, errors.New("mint gas limit must be positive")
+	}
+
+	if reserveMints < 0 {
+		return nil, errors.New("reserve mints must not be negative")
+	}
+
+	return &BalanceMonitorController{
+		signerPool:    signerPool,
+		feeController: feeController,
+		notifier:      notifier,
+		database:      database,
+		mintGasLimit:  mintGasLimit,
+		reserveMints:  reserveMints,
+	}, nil
+}
+
+// getPendingMints counts the purchases which are to be minted, excluding the sent mints the balances already pay for.
+func (controller *BalanceMonitorController) getPendingMints() (int64, error) {
+	var count int64
+
+	err := controller.database.Model(&model.BTCTransaction{}).
+		Where("status in (?)", []int8{model.TRANSACTION_STATUS_WAITING_FOR_GAS, model.TRANSACTION_STATUS_WAITING_FOR_BALANCE}).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (controller *BalanceMonitorController) getMintGasLimit() (uint64, error) {
+	transaction := new(model.BTCTransaction)
+
+	err := controller.database.Where("mint_gas_limit > 0 and mint_sent_at is not null").Order("mint_sent_at desc").First(transaction).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return controller.mintGasLimit, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return transaction.MintGasLimit, nil
+}
+
+func (controller *BalanceMonitorController) getBalanceReport() (*BalanceReport, error) {
+	fees, err := controller.feeController.GetFees()
+
+	if err != nil {
+		return nil, err
+	}
+
+	mintGasLimit, err := controller.getMintGasLimit()
+
+	if err != nil {
+		return nil, err
+	}
+
+	pendingMints, err := controller.getPendingMints()
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BalanceReport{
+		MintCost:     fees.MaxCost(mintGasLimit),
+		PendingMints: pendingMints,
+		Balances:     make([]AccountBalance, 0, len(controller.signerPool.Signers())),
+	}
+
+	if report.MintCost.Sign() != 1 {
+		return nil, errors.New("mint cost must be positive")
+	}
+
+	for _, signer := range controller.signerPool.Signers() {
+		balance, err := controller.signerPool.GetBalance(signer.Address())
+
+		if err != nil {
+			return nil, err
+		}
+
+		report.Balances = append(report.Balances, AccountBalance{signer.Address(), balance})
+		report.AffordableMints += new(big.Int).Quo(balance, report.MintCost).Int64()
+	}
+
+	return report, nil
+}
+
+func (report BalanceReport) String() string {
+	balances := make([]string, 0, len(report.Balances))
+
+	for _, accountBalance := range report.Balances {
+		balances = append(balances, fmt.Sprintf("%s: %s wei", accountBalance.Address.Hex(), accountBalance.Balance))
+	}
+
+	return fmt.Sprintf(
+		"the signing accounts can pay for %d mints of %s wei, %d are pending (%s)",
+		report.AffordableMints,
+		report.MintCost,
+		report.PendingMints,
+		strings.Join(balances, ", "),
+	)
+}
+
+// CheckBalances pauses or resumes the signer pool as the balances allow, alerting the operator on changes.
+func (controller *BalanceMonitorController) CheckBalances() (*BalanceReport, error) {
+	report, err := controller.getBalanceReport()
+
+	if err != nil {
+		return nil, err
+	}
+
+	isLow := report.AffordableMints < report.PendingMints+controller.reserveMints
+
+	if isLow && !controller.isLow {
+		controller.notify("Low balance: " + report.String())
+	}
+
+	controller.isLow = isLow
+
+	if report.AffordableMints == 0 && !controller.signerPool.IsPaused() {
+		controller.signerPool.Pause()
+		controller.notify("Minting paused: " + report.String())
+	}
+
+	if report.AffordableMints > 0 && controller.signerPool.IsPaused() {
+		controller.signerPool.Resume()
+		controller.notify("Minting resumed: " + report.String())
+	}
+
+	return report, nil
+}
+
+func (controller *BalanceMonitorController) notify(message string) {
+	if err := controller.notifier.Notify(message); err != nil {
+		log.Println("UNABLE TO SEND ALERT", message, err)
+	}
+}
+
+// Monitor checks the balances every checkInterval.
+func (controller *BalanceMonitorController) Monitor(checkInterval time.Duration) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := controller.CheckBalances(); err != nil {
+			log.Println("UNABLE TO CHECK BALANCES", err)
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"MCW-btc-module/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+type testNotifier struct {
+	messages []string
+}
+
+func (notifier *testNotifier) Notify(message string) error {
+	notifier.messages = append(notifier.messages, strings.SplitN(message, ":", 2)[0])
+
+	return nil
+}
+
+func TestMakeBalanceMonitorController(t *testing.T) {
+	_, err := MakeBalanceMonitorController(nil, FeeController{}, LogNotifier{}, nil, 0, 0)
+
+	assert.EqualError(t, err, "mint gas limit must be positive")
+
+	_, err = MakeBalanceMonitorController(nil, FeeController{}, LogNotifier{}, nil, 250000, -1)
+
+	assert.EqualError(t, err, "reserve mints must not be negative")
+}
+
+func TestBalanceMonitorController_CheckBalances(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.DropTableIfExists(model.BTCTransaction{})
+	db.AutoMigrate(model.BTCTransaction{})
+
+	db.Create(&model.BTCTransaction{Status: model.TRANSACTION_STATUS_WAITING_FOR_GAS})
+	db.Create(&model.BTCTransaction{Status: model.TRANSACTION_STATUS_WAITING_FOR_BALANCE})
+	db.Create(&model.BTCTransaction{Status: model.TRANSACTION_STATUS_MINTING})
+
+	infuraController := testInfuraController()
+	signers := testTransactionSigners(2)
+	pool, _ := MakeSignerPool(infuraController, signers, SignerPoolRoundRobin)
+	feeController, _ := MakeFeeController(infuraController, false, big.NewInt(0), big.NewInt(0))
+	notifier := &testNotifier{}
+
+	// A mint costs 100000 gas at 1 gwei, i.e. 10^14 wei. The balances must cover the 2 waiting mints and 2 more.
+	controller, err := MakeBalanceMonitorController(pool, feeController, notifier, db, 100000, 2)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	balances := map[common.Address]*big.Int{}
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		batchResponder(func(request *http.Request) (*http.Response, error) {
+			requestBody := new(requestPayload)
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return nil, err
+			}
+
+			result := "0x3b9aca00"
+
+			if requestBody.Method == "eth_getBalance" {
+				result = hexutil.EncodeBig(balances[common.HexToAddress(requestBody.Params[0].(string))])
+			}
+
+			return httpmock.NewJsonResponse(http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "result": result})
+		}),
+	)
+
+	mint := big.NewInt(100000000000000)
+	// The second account is always half a mint short of its last mint.
+	setBalances := func(first int64, second int64) {
+		balances[signers[0].Address()] = new(big.Int).Mul(mint, big.NewInt(first))
+		balances[signers[1].Address()] = new(big.Int).Add(new(big.Int).Mul(mint, big.NewInt(second)), big.NewInt(50000000000000))
+	}
+
+	setBalances(3, 1)
+
+	report, err := controller.CheckBalances()
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, mint, report.MintCost)
+		assert.Equal(t, int64(2), report.PendingMints)
+		assert.Equal(t, int64(4), report.AffordableMints)
+		assert.Empty(t, notifier.messages)
+	}
+
+	setBalances(3, 0)
+
+	_, err = controller.CheckBalances()
+	assert.NoError(t, err)
+
+	// The alert isn't repeated while the balances stay low.
+	_, err = controller.CheckBalances()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Low balance"}, notifier.messages)
+	assert.False(t, pool.IsPaused())
+
+	setBalances(0, 0)
+
+	_, err = controller.CheckBalances()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Low balance", "Minting paused"}, notifier.messages)
+	assert.True(t, pool.IsPaused())
+
+	_, err = pool.Acquire(big.NewInt(1))
+
+	assert.Equal(t, ErrSignerPoolPaused, err)
+
+	setBalances(10, 0)
+
+	_, err = controller.CheckBalances()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Low balance", "Minting paused", "Minting resumed"}, notifier.messages)
+	assert.False(t, pool.IsPaused())
+
+	setBalances(1, 1)
+
+	_, err = controller.CheckBalances()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Low balance", "Minting paused", "Minting resumed", "Low balance"}, notifier.messages)
+
+	// Once a mint has been sent, mints cost the gas estimated for the latest one.
+	sentAt := time.Now()
+	db.Create(&model.BTCTransaction{MintGasLimit: 200000, MintSentAt: &sentAt, Status: model.TRANSACTION_STATUS_SUCCESS})
+
+	report, err = controller.CheckBalances()
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, new(big.Int).Mul(mint, big.NewInt(2)), report.MintCost)
+	}
+}
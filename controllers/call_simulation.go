@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	revertReasonSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector        = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// RevertError is the revert of a simulated call. The reason is empty if the contract gave none.
+type RevertError struct {
+	Reason string
+}
+
+func (err *RevertError) Error() string {
+	if err.Reason == "" {
+		return "execution reverted"
+	}
+
+	return "execution reverted: " + err.Reason
+}
+
+type simulatedCallParameter struct {
+	From common.Address `json:"from"`
+	To   common.Address `json:"to"`
+	Gas  hexutil.Uint64 `json:"gas,omitempty"`
+	Data hexutil.Bytes  `json:"data"`
+}
+
+// decodeRevertReason decodes the Error(string) or Panic(uint256) the call reverted with.
+func decodeRevertReason(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+
+	argumentType, _ := abi.NewType("string", "", nil)
+
+	if bytes.Equal(data[:4], panicSelector) {
+		argumentType, _ = abi.NewType("uint256", "", nil)
+	} else if !bytes.Equal(data[:4], revertReasonSelector) {
+		return ""
+	}
+
+	values, err := abi.Arguments{{Type: argumentType}}.UnpackValues(data[4:])
+
+	if err != nil || len(values) != 1 {
+		return ""
+	}
+
+	if reason, ok := values[0].(string); ok {
+		return reason
+	}
+
+	return fmt.Sprintf("panic: 0x%x", values[0])
+}
+
+// revertData returns the data of the revert, which nodes pass either as is or prefixed, e.g. "Reverted 0x...".
+func (infuraError infuraError) revertData() []byte {
+	var data string
+
+	if err := json.Unmarshal(infuraError.Data, &data); err != nil {
+		return nil
+	}
+
+	decoded, err := hexutil.Decode(strings.TrimPrefix(data, "Reverted "))
+
+	if err != nil {
+		return nil
+	}
+
+	return decoded
+}
+
+func (infuraError infuraError) isRevert() bool {
+	return infuraError.Code == 3 || strings.Contains(strings.ToLower(infuraError.Message), "revert")
+}
+
+// callError returns *RevertError if the call reverted.
+func (infuraError infuraError) callError() error {
+	if !infuraError.isRevert() {
+		return errors.New(infuraError.Message)
+	}
+
+	reason := decodeRevertReason(infuraError.revertData())
+
+	if reason == "" {
+		reason = strings.TrimPrefix(strings.TrimPrefix(infuraError.Message, "execution reverted"), ": ")
+	}
+
+	return &RevertError{reason}
+}
+
+// simulateCall runs the call from the account with eth_call on the pending state, so that a transaction making it
+// is only sent if it succeeds. It returns *RevertError if the call reverts.
+func (controller InfuraController) simulateCall(from common.Address, to common.Address, gasLimit uint64, data []byte) error {
+	response := new(infuraResponse)
+
+	err := controller.postInfura(makeRequestPayload(
+		"eth_call",
+		[]interface{}{
+			simulatedCallParameter{
+				From: from,
+				To:   to,
+				Gas:  hexutil.Uint64(gasLimit),
+				Data: data,
+			},
+			"pending",
+		},
+	), response)
+
+	if err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return response.Error.callError()
+	}
+
+	return nil
+}
+
+// estimateGas estimates the gas of the call from the account, made like simulateCall. It returns *RevertError if the call reverts.
+func (controller InfuraController) estimateGas(from common.Address, to common.Address, data []byte) (uint64, error) {
+	response := new(infuraResponse)
+
+	err := controller.postInfura(makeRequestPayload(
+		"eth_estimateGas",
+		[]interface{}{
+			simulatedCallParameter{
+				From: from,
+				To:   to,
+				Data: data,
+			},
+		},
+	), response)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if response.Error != nil {
+		return 0, response.Error.callError()
+	}
+
+	return hexutil.DecodeUint64(response.Result)
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func packRevertData(selector []byte, typeName string, value interface{}) []byte {
+	argumentType, _ := abi.NewType(typeName, "", nil)
+	data, _ := abi.Arguments{{Type: argumentType}}.Pack(value)
+
+	return append(append([]byte{}, selector...), data...)
+}
+
+func TestDecodeRevertReason(t *testing.T) {
+	assert.Equal(t, "sale is paused", decodeRevertReason(packRevertData(revertReasonSelector, "string", "sale is paused")))
+	assert.Equal(t, "panic: 0x11", decodeRevertReason(packRevertData(panicSelector, "uint256", big.NewInt(0x11))))
+	assert.Equal(t, "", decodeRevertReason([]byte{1, 2, 3, 4, 5}))
+	assert.Equal(t, "", decodeRevertReason(revertReasonSelector))
+	assert.Equal(t, "", decodeRevertReason(nil))
+}
+
+func TestInfuraController_SimulateCall(t *testing.T) {
+	controller := testInfuraController()
+	from := common.HexToAddress("0x123")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	response := `{"jsonrpc": "2.0", "result": "0x"}`
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		func(request *http.Request) (*http.Response, error) {
+			requestBody := new(struct {
+				Method string        `json:"method"`
+				Params []interface{} `json:"params"`
+			})
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return nil, err
+			}
+
+			call := requestBody.Params[0].(map[string]interface{})
+
+			// The call is made from the account on the pending state.
+			if requestBody.Method != "eth_call" || call["from"] != hexutil.Encode(from.Bytes()) || call["gas"] != "0x7a120" || requestBody.Params[1] != "pending" {
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "invalid argument"}}`), nil
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, response), nil
+		},
+	)
+
+	assert.NoError(t, controller.simulateCall(from, common.HexToAddress("0x456"), 500000, []byte{1, 2, 3}))
+
+	response = `{"jsonrpc": "2.0", "error": {"code": 3, "message": "execution reverted: sale is paused", "data": "` +
+		hexutil.Encode(packRevertData(revertReasonSelector, "string", "sale is paused")) + `"}}`
+
+	err := controller.simulateCall(from, common.HexToAddress("0x456"), 500000, []byte{1, 2, 3})
+
+	assert.Equal(t, &RevertError{"sale is paused"}, err)
+	assert.EqualError(t, err, "execution reverted: sale is paused")
+
+	response = `{"jsonrpc": "2.0", "error": {"code": -32015, "message": "VM execution error: revert", "data": "Reverted 0x"}}`
+
+	err = controller.simulateCall(from, common.HexToAddress("0x456"), 500000, []byte{1, 2, 3})
+
+	assert.Equal(t, &RevertError{"VM execution error: revert"}, err)
+
+	response = `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "execution reverted"}}`
+
+	err = controller.simulateCall(from, common.HexToAddress("0x456"), 500000, []byte{1, 2, 3})
+
+	assert.Equal(t, &RevertError{}, err)
+	assert.EqualError(t, err, "execution reverted")
+
+	response = `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "header not found"}}`
+
+	err = controller.simulateCall(from, common.HexToAddress("0x456"), 500000, []byte{1, 2, 3})
+
+	assert.EqualError(t, err, "header not found")
+	_, isRevert := err.(*RevertError)
+	assert.False(t, isRevert)
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	LegacyTransactionType     = 0
+	DynamicFeeTransactionType = 2
+)
+
+// DynamicFeeTransaction is the EIP-1559 transaction, which the vendored go-ethereum predates.
+type DynamicFeeTransaction struct {
+	ChainID              *big.Int
+	Nonce                uint64
+	MaxPriorityFeePerGas *big.Int
+	MaxFeePerGas         *big.Int
+	Gas                  uint64
+	To                   common.Address
+	Value                *big.Int
+	Data                 []byte
+	V, R, S              *big.Int
+}
+
+func (transaction DynamicFeeTransaction) fields() []interface{} {
+	return []interface{}{
+		transaction.ChainID,
+		transaction.Nonce,
+		transaction.MaxPriorityFeePerGas,
+		transaction.MaxFeePerGas,
+		transaction.Gas,
+		transaction.To,
+		transaction.Value,
+		transaction.Data,
+		// Access list
+		[]interface{}{},
+	}
+}
+
+func encodeTypedTransaction(fields []interface{}) ([]byte, error) {
+	payload, err := rlp.EncodeToBytes(fields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{DynamicFeeTransactionType}, payload...), nil
+}
+
+func (transaction DynamicFeeTransaction) SigningHash() (common.Hash, error) {
+	encoded, err := encodeTypedTransaction(transaction.fields())
+
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// WithSignature returns the transaction signed by the 65 bytes [R || S || V] signature, where V is 0 or 1.
+func (transaction DynamicFeeTransaction) WithSignature(signature []byte) *DynamicFeeTransaction {
+	transaction.R = new(big.Int).SetBytes(signature[:32])
+	transaction.S = new(big.Int).SetBytes(signature[32:64])
+	transaction.V = new(big.Int).SetBytes(signature[64:])
+
+	return &transaction
+}
+
+// MarshalBinary encodes the signed transaction for eth_sendRawTransaction.
+func (transaction DynamicFeeTransaction) MarshalBinary() ([]byte, error) {
+	return encodeTypedTransaction(append(transaction.fields(), transaction.V, transaction.R, transaction.S))
+}
+
+func (transaction DynamicFeeTransaction) Hash() (common.Hash, error) {
+	encoded, err := transaction.MarshalBinary()
+
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(encoded), nil
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySigner_SignDynamicFeeTransaction(t *testing.T) {
+	signer := testKeySigner()
+
+	signedTransaction, err := signer.SignDynamicFeeTransaction(big.NewInt(5), DynamicFeeTransaction{
+		Nonce:                7,
+		MaxPriorityFeePerGas: big.NewInt(1500000000),
+		MaxFeePerGas:         big.NewInt(30000000000),
+		Gas:                  210000,
+		To:                   common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Value:                big.NewInt(0),
+		Data:                 []byte{1, 2, 3},
+	})
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	rawTransaction, err := signedTransaction.MarshalBinary()
+
+	// The same transaction signed by go-ethereum's London signer.
+	if assert.NoError(t, err) {
+		assert.Equal(
+			t,
+			"0x02f86f05078459682f008506fc23ac00830334509412345678901234567890123456789012345678908083010203c001a0b1e29097d5d9eed5e55f4072724ba7c461a1bfe01ad95b58daef8b5909c9087aa0163c8d97cd84757cd09b171c6bd02e8ba24bd7447bd38343d02890dc048fc7d3",
+			hexutil.Bytes(rawTransaction).String(),
+		)
+	}
+
+	hash, _ := signedTransaction.SigningHash()
+	signature := append(append(common.LeftPadBytes(signedTransaction.R.Bytes(), 32), common.LeftPadBytes(signedTransaction.S.Bytes(), 32)...), byte(signedTransaction.V.Uint64()))
+
+	publicKey, err := crypto.SigToPub(hash.Bytes(), signature)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, signer.Address(), crypto.PubkeyToAddress(*publicKey))
+	}
+}
+
+func TestTransactionSigner_SignRawTransaction(t *testing.T) {
+	transactionSigner := TransactionSigner{
+		chainID:       big.NewInt(5),
+		accountSigner: testKeySigner(),
+	}
+
+	to := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	rawTransaction, hash, err := transactionSigner.SignRawTransaction(
+		7,
+		to,
+		210000,
+		&TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(30000000000), MaxPriorityFeePerGas: big.NewInt(1500000000)},
+		[]byte{1, 2, 3},
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, byte(DynamicFeeTransactionType), rawTransaction[0])
+		assert.Equal(t, crypto.Keccak256Hash(rawTransaction), hash)
+	}
+
+	rawTransaction, hash, err = transactionSigner.SignRawTransaction(
+		7,
+		to,
+		210000,
+		&TransactionFees{Type: LegacyTransactionType, GasPrice: big.NewInt(20000000000)},
+		[]byte{1, 2, 3},
+	)
+
+	if assert.NoError(t, err) {
+		// Legacy transactions are RLP lists.
+		assert.True(t, rawTransaction[0] >= 0xc0)
+		assert.Equal(t, crypto.Keccak256Hash(rawTransaction), hash)
+	}
+}
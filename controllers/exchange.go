@@ -1,24 +1,31 @@
 package controllers
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
-	"math"
 	"math/big"
+	"strconv"
 	"sync"
+	"time"
 
 	"MCW-btc-module/helpers"
 	"MCW-btc-module/model"
 
 	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/jinzhu/gorm"
 )
 
 type ExchangeController struct {
 	MonitoringController
 	TokenManagementController
+	RateOracleController
+	QuoteController
+	RateCircuitBreakerController
+	GasBumpingController
+	receiptController ReceiptController
 	database          *gorm.DB
 	xpub              *hdkeychain.ExtendedKey
 	isTestnet         bool
@@ -29,6 +36,11 @@ type ExchangeController struct {
 func MakeExchangeController(
 	monitoringController MonitoringController,
 	tokenManagementController TokenManagementController,
+	rateOracleController RateOracleController,
+	quoteController QuoteController,
+	rateCircuitBreakerController RateCircuitBreakerController,
+	receiptController ReceiptController,
+	gasBumpingController GasBumpingController,
 	database *gorm.DB,
 	xpubString string,
 	isTestnet bool,
@@ -43,43 +55,46 @@ func MakeExchangeController(
 	database.Order("index desc").First(&latestTransaction)
 
 	return &ExchangeController{
-		MonitoringController:      monitoringController,
-		TokenManagementController: tokenManagementController,
-		database:                  database,
-		xpub:                      xpub,
-		isTestnet:                 isTestnet,
-		currentChildIndex:         latestTransaction.Index,
-		indexMutex:                &sync.Mutex{},
+		MonitoringController:         monitoringController,
+		TokenManagementController:    tokenManagementController,
+		RateOracleController:         rateOracleController,
+		QuoteController:              quoteController,
+		RateCircuitBreakerController: rateCircuitBreakerController,
+		GasBumpingController:         gasBumpingController,
+		receiptController:            receiptController,
+		database:                     database,
+		xpub:                         xpub,
+		isTestnet:                    isTestnet,
+		currentChildIndex:            latestTransaction.Index,
+		indexMutex:                   &sync.Mutex{},
 	}, nil
 }
 
 func (controller ExchangeController) getExchangeRate() (float64, error) {
-	type ShapeshiftResponse struct {
-		Rate big.Float `json:"rate, string"`
-	}
-	_, responseBytes, err := helpers.Get("https://shapeshift.io/rate/btc_eth", helpers.Headers{})
+	return controller.RateOracleController.GetExchangeRate()
+}
 
-	if err != nil {
-		return 0, err
-	}
+func (controller *ExchangeController) CreateTransactionEntry(ethereumAddress string) (*model.BTCTransaction, bool, error) {
+	transaction := new(model.BTCTransaction)
 
-	response := new(ShapeshiftResponse)
+	err := controller.database.Where("ethereum_address = ? and bitcoin_address <> ''", ethereumAddress).Order("id desc", false).First(transaction).Error
 
-	if err := json.Unmarshal(responseBytes, &response); err != nil {
-		return 0, err
-	}
+	isPending := transaction.Status == model.TRANSACTON_STATUS_NEW || transaction.Status == model.TRANSACTION_STATUS_BELOW_MINIMUM
 
-	value, _ := response.Rate.Float64()
+	if err == nil && isPending && transaction.BitcoinAddress != "" {
+		// Nothing has been deposited under the expired quote yet, so the investor gets a fresh one.
+		if transaction.SatoshisTransferred == 0 && controller.QuoteController.IsQuoteExpired(transaction, time.Now()) {
+			if err := controller.issueQuote(transaction); err != nil {
+				return nil, false, err
+			}
 
-	return value, nil
-}
-
-func (controller *ExchangeController) CreateTransactionEntry(ethereumAddress string) (*model.BTCTransaction, bool, error) {
-	transaction := new(model.BTCTransaction)
+			if err := controller.database.Save(transaction).Error; err != nil {
+				return nil, false, err
+			}
 
-	err := controller.database.Where("ethereum_address = ?", ethereumAddress).Order("id desc", false).First(transaction).Error
+			controller.recordRate(transaction, model.RATE_HISTORY_KIND_QUOTE, transaction.QuoteRate, transaction.QuoteTokenRate)
+		}
 
-	if err == nil && transaction.Status == model.TRANSACTON_STATUS_NEW && transaction.BitcoinAddress != "" {
 		return transaction, false, nil
 	}
 
@@ -102,13 +117,184 @@ func (controller *ExchangeController) CreateTransactionEntry(ethereumAddress str
 		Status:          model.TRANSACTON_STATUS_NEW,
 	}
 
+	if err := controller.issueQuote(transaction); err != nil {
+		return nil, false, err
+	}
+
 	if err := controller.UpdateBTCAddress(ethereumAddress, address); err != nil {
 		return nil, false, err
 	}
 
-	err = controller.database.Create(transaction).Error
+	if err := controller.database.Create(transaction).Error; err != nil {
+		return nil, false, err
+	}
+
+	controller.recordRate(transaction, model.RATE_HISTORY_KIND_QUOTE, transaction.QuoteRate, transaction.QuoteTokenRate)
+
+	return transaction, true, nil
+}
+
+func (controller ExchangeController) issueQuote(transaction *model.BTCTransaction) error {
+	rate, err := controller.getExchangeRate()
+
+	if err != nil {
+		return err
+	}
+
+	tokenRate, err := controller.TokenManagementController.GetTokenRate()
+
+	if err != nil {
+		return err
+	}
+
+	phase, err := controller.TokenManagementController.GetPhase()
+
+	if err != nil {
+		return err
+	}
+
+	controller.QuoteController.IssueQuote(transaction, rate, tokenRate, phase)
+
+	return nil
+}
+
+func (controller ExchangeController) recordRate(transaction *model.BTCTransaction, kind string, rate float64, tokenRate string) {
+	record := &model.RateHistory{
+		TransactionID: transaction.ID,
+		Kind:          kind,
+		ExchangeRate:  strconv.FormatFloat(rate, 'f', -1, 64),
+		TokenRate:     tokenRate,
+	}
+
+	if err := controller.database.Create(record).Error; err != nil {
+		log.Println(err)
+	}
+}
+
+// GetRateHistory returns the rates recorded between from and to, the latest first. Zero times are not bounded.
+func (controller ExchangeController) GetRateHistory(from time.Time, to time.Time, limit int) ([]model.RateHistory, error) {
+	records := make([]model.RateHistory, 0)
+
+	query := controller.database.Order("created_at desc, id desc")
+
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+
+	if !to.IsZero() {
+		query = query.Where("created_at <= ?", to)
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	err := query.Find(&records).Error
 
-	return transaction, true, err
+	return records, err
+}
+
+// getPurchaseRates returns the BTC/ETH rate and the token rate the deposit is exchanged at, and when the rate was obtained.
+// The crowdsale always sells at its current token rate, so quotes made at another token rate aren't honored.
+// ErrQuoteExpired is returned if the quote is not honored anymore and the policy does not allow requoting.
+func (controller ExchangeController) getPurchaseRates(transaction *model.BTCTransaction) (float64, *TokenRate, time.Time, error) {
+	phase, err := controller.TokenManagementController.GetPhase()
+
+	if err != nil {
+		return 0, nil, time.Time{}, err
+	}
+
+	tokenRate, err := controller.TokenManagementController.GetTokenRate()
+
+	if err != nil {
+		return 0, nil, time.Time{}, err
+	}
+
+	if controller.QuoteController.IsQuoteHonored(transaction, phase, time.Now()) && transaction.QuoteTokenRate == tokenRate.String() {
+		rate, _, err := controller.QuoteController.getQuotedRates(transaction)
+
+		return rate, tokenRate, transaction.QuoteExpiresAt.Add(-controller.QuoteController.validity), err
+	}
+
+	if controller.QuoteController.expiredPolicy != QuotePolicyRequote {
+		return 0, nil, time.Time{}, ErrQuoteExpired
+	}
+
+	rate, err := controller.getExchangeRate()
+
+	return rate, tokenRate, time.Now(), err
+}
+
+func (controller ExchangeController) getLastAcceptedRate() (float64, error) {
+	record := new(model.RateHistory)
+
+	err := controller.database.Where("kind = ?", model.RATE_HISTORY_KIND_PURCHASE).Order("id desc").First(record).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(record.ExchangeRate, 64)
+}
+
+// checkPurchaseRate runs the rate through the circuit breaker before anything is minted.
+func (controller ExchangeController) checkPurchaseRate(rate float64, rateTime time.Time) error {
+	lastAcceptedRate, err := controller.getLastAcceptedRate()
+
+	if err != nil {
+		return err
+	}
+
+	var acceptedRates map[string]float64
+
+	// Too few sources agreeing trips the breaker as well.
+	if controller.RateCircuitBreakerController.isMedianChecked() {
+		if acceptedRates, err = controller.RateOracleController.GetAcceptedRates(); err != nil {
+			log.Printf("RATE ALARM: %s\n", err)
+			return err
+		}
+	}
+
+	err = controller.RateCircuitBreakerController.CheckRate(rate, rateTime, lastAcceptedRate, acceptedRates, time.Now())
+
+	if err != nil {
+		log.Printf("RATE ALARM: %s\n", err)
+	}
+
+	return err
+}
+
+func (controller ExchangeController) holdForReview(transaction *model.BTCTransaction, err error) {
+	transaction.Error = err.Error()
+	transaction.Status = model.TRANSACTION_STATUS_NEEDS_REVIEW
+	controller.database.Save(transaction)
+}
+
+func (controller ExchangeController) rejectExpiredQuote(transaction *model.BTCTransaction) {
+	transaction.Error = ErrQuoteExpired.Error()
+
+	if controller.QuoteController.expiredPolicy == QuotePolicyRefund {
+		transaction.SatoshisRefunded = transaction.SatoshisTransferred
+		transaction.Status = model.TRANSACTION_STATUS_REFUND
+	} else {
+		transaction.Status = model.TRANSACTION_STATUS_QUOTE_EXPIRED
+	}
+
+	controller.database.Save(transaction)
+}
+
+func (controller ExchangeController) GetTransactionByBitcoinAddress(bitcoinAddress string) (*model.BTCTransaction, error) {
+	transaction := new(model.BTCTransaction)
+
+	if err := controller.database.Where("bitcoin_address = ?", bitcoinAddress).First(transaction).Error; err != nil {
+		return nil, errors.New("transaction not found")
+	}
+
+	return transaction, nil
 }
 
 func (controller ExchangeController) UpdateBTCAddress(ethereumAddress string, btcAddress string) error {
@@ -133,60 +319,394 @@ func (controller *ExchangeController) BuyTokens(transaction *model.BTCTransactio
 	}
 
 	defer startAgain()
-	rate, err := controller.getExchangeRate()
 
-	if err != nil {
-		transaction.Error = err.Error()
-		transaction.Status = model.TRANSACTION_STATUS_ERROR
+	receiver := common.HexToAddress(transaction.EthereumAddress)
+
+	var acceptedWei, refundedWei *big.Int
+	var rate float64
+	var tokenRate *TokenRate
+	var rateTime time.Time
+
+	heldSatoshis := int64(0)
+
+	if transaction.Status == model.TRANSACTION_STATUS_BELOW_MINIMUM {
+		heldSatoshis = transaction.SatoshisTransferred
+	}
+
+	// Deposits below the minimal investment are held until further transfers to the same address add up to it.
+	for {
+		receivedSatoshis, err := controller.MonitoringController.waitForTransferAbove(transaction.BitcoinAddress, heldSatoshis)
+
+		if err != nil {
+			transaction.Error = err.Error()
+			transaction.Status = model.TRANSACTION_STATUS_ERROR
+			controller.database.Save(transaction)
+			return
+		}
+
+		transaction.SatoshisTransferred = receivedSatoshis
+		controller.database.Save(transaction)
+
+		rate, tokenRate, rateTime, err = controller.getPurchaseRates(transaction)
+
+		if err == ErrQuoteExpired {
+			controller.rejectExpiredQuote(transaction)
+			return
+		}
+
+		if err != nil {
+			transaction.Error = err.Error()
+			transaction.Status = model.TRANSACTION_STATUS_ERROR
+			controller.database.Save(transaction)
+			return
+		}
+
+		if err := controller.checkPurchaseRate(rate, rateTime); err != nil {
+			controller.holdForReview(transaction, err)
+			return
+		}
+
+		acceptedWei, refundedWei, err = controller.TokenManagementController.CheckInvestmentLimits(receiver, helpers.SatoshisToWei(receivedSatoshis, rate))
+
+		if err == ErrBelowMinimalInvestment {
+			heldSatoshis = receivedSatoshis
+			transaction.Status = model.TRANSACTION_STATUS_BELOW_MINIMUM
+			controller.database.Save(transaction)
+			continue
+		}
+
+		if err != nil {
+			transaction.Error = err.Error()
+			transaction.Status = model.TRANSACTION_STATUS_ERROR
+			controller.database.Save(transaction)
+			return
+		}
+
+		break
+	}
+
+	controller.completePurchase(transaction, rate, tokenRate, acceptedWei, refundedWei)
+}
+
+// completePurchase mints tokens for the accepted part of the purchase and records the refundable part.
+func (controller *ExchangeController) completePurchase(
+	transaction *model.BTCTransaction,
+	rate float64,
+	tokenRate *TokenRate,
+	acceptedWei *big.Int,
+	refundedWei *big.Int,
+) {
+	tokensAmount := big.NewInt(0)
+
+	if acceptedWei.Sign() == 1 {
+		cappedWei, cappedTokensAmount, err := controller.TokenManagementController.CapByTokensLeft(acceptedWei, tokenRate)
+
+		if err != nil {
+			transaction.Error = err.Error()
+			transaction.Status = model.TRANSACTION_STATUS_ERROR
+			controller.database.Save(transaction)
+			return
+		}
+
+		refundedWei = big.NewInt(0).Add(refundedWei, big.NewInt(0).Sub(acceptedWei, cappedWei))
+		acceptedWei, tokensAmount = cappedWei, cappedTokensAmount
+	}
+
+	transaction.ExchangeRate = strconv.FormatFloat(rate, 'f', -1, 64)
+	transaction.TokenRate = tokenRate.String()
+	transaction.AcceptedWei = model.NewBigInt(acceptedWei)
+	transaction.RefundedWei = model.NewBigInt(refundedWei)
+	transaction.SatoshisRefunded = helpers.WeiToSatoshis(refundedWei, rate)
+	controller.database.Save(transaction)
+
+	controller.recordRate(transaction, model.RATE_HISTORY_KIND_PURCHASE, rate, transaction.TokenRate)
+
+	if acceptedWei.Sign() == 0 {
+		transaction.Status = model.TRANSACTION_STATUS_REFUND
 		controller.database.Save(transaction)
 		return
 	}
 
-	receivedBTC, err := controller.MonitoringController.waitForTransfer(transaction.BitcoinAddress)
+	transaction.TokensAmount = model.NewBigInt(tokensAmount)
+
+	if controller.mintPurchase(transaction) {
+		controller.trackMintTransaction(transaction)
+	}
+}
+
+// mintPurchase mints the tokens bought with the accepted wei and tells whether the mint transaction has been sent.
+// Purchases wait for gas while the network fees are above the ceiling, unless the operator has forced the mint,
+// and for balance while the signing accounts can't pay for the mint.
+func (controller ExchangeController) mintPurchase(transaction *model.BTCTransaction) bool {
+	mintTokens := controller.TokenManagementController.MintTokens
+
+	if transaction.MintIsForced {
+		mintTokens = controller.TokenManagementController.ForceMintTokens
+	}
+
+	mintTransaction, err := mintTokens(common.HexToAddress(transaction.EthereumAddress), transaction.AcceptedWei.Big())
+
+	if err == ErrFeesAboveCeiling {
+		transaction.Status = model.TRANSACTION_STATUS_WAITING_FOR_GAS
+		controller.database.Save(transaction)
+		return false
+	}
+
+	if err == ErrSignerPoolPaused || err == ErrNoFundedSigner {
+		transaction.Status = model.TRANSACTION_STATUS_WAITING_FOR_BALANCE
+		controller.database.Save(transaction)
+		return false
+	}
+
+	if revertError, ok := err.(*RevertError); ok {
+		transaction.MintRevertReason = revertError.Reason
+	}
 
 	if err != nil {
 		transaction.Error = err.Error()
 		transaction.Status = model.TRANSACTION_STATUS_ERROR
 		controller.database.Save(transaction)
-		return
+		return false
 	}
 
-	transaction.AmountTransferred = receivedBTC
+	sentAt := time.Now()
+
+	transaction.MintTransactionType = mintTransaction.Fees.Type
+	transaction.MintGasLimit = mintTransaction.GasLimit
+	transaction.MintGasPrice = model.NewBigInt(mintTransaction.Fees.GasPrice)
+	transaction.MintMaxFeePerGas = model.NewBigInt(mintTransaction.Fees.MaxFeePerGas)
+	transaction.MintMaxPriorityFeePerGas = model.NewBigInt(mintTransaction.Fees.MaxPriorityFeePerGas)
+	transaction.MintFrom = mintTransaction.From.Hex()
+	transaction.MintTransactionHash = mintTransaction.Hash.Hex()
+	transaction.MintNonce = mintTransaction.Nonce
+	transaction.MintData = hexutil.Bytes(mintTransaction.Data).String()
+	transaction.MintSentAt = &sentAt
+	transaction.Status = model.TRANSACTION_STATUS_MINTING
 	controller.database.Save(transaction)
 
-	receivedEth := receivedBTC * rate
+	return true
+}
 
-	exchangeRate, err := controller.TokenManagementController.GetTokenExchangeRate()
+// MintWaitingPurchases mints the purchases waiting for gas or balance, oldest first, once the network fees drop
+// below the ceiling or the operator forces their mints, and the signing accounts are funded.
+func (controller ExchangeController) MintWaitingPurchases(pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		controller.mintWaitingPurchases()
+
+		<-ticker.C
+	}
+}
+
+func (controller ExchangeController) mintWaitingPurchases() {
+	transactions := make([]model.BTCTransaction, 0)
+
+	err := controller.database.
+		Where("status in (?)", []int8{model.TRANSACTION_STATUS_WAITING_FOR_GAS, model.TRANSACTION_STATUS_WAITING_FOR_BALANCE}).
+		Order("id").
+		Find(&transactions).Error
 
 	if err != nil {
-		transaction.Error = err.Error()
-		transaction.Status = model.TRANSACTION_STATUS_ERROR
-		controller.database.Save(transaction)
+		log.Println(err)
 		return
 	}
 
-	bigWei := big.NewFloat(0).Mul(big.NewFloat(receivedEth), big.NewFloat(math.Pow(10, 18)))
+	isAboveFeeCeiling := false
+
+	for index := range transactions {
+		transaction := &transactions[index]
+
+		// Only forced mints go on once the fees turned out to be too high.
+		if isAboveFeeCeiling && !transaction.MintIsForced {
+			continue
+		}
+
+		if controller.mintPurchase(transaction) {
+			go controller.trackMintTransaction(transaction)
+		}
+
+		// None of the rest can be paid for either.
+		if transaction.Status == model.TRANSACTION_STATUS_WAITING_FOR_BALANCE {
+			return
+		}
+
+		if transaction.Status == model.TRANSACTION_STATUS_WAITING_FOR_GAS {
+			isAboveFeeCeiling = true
+		}
+	}
+}
+
+// ForceMint makes the purchase waiting for gas mint on the next attempt, whatever the network fees are.
+func ForceMint(database *gorm.DB, transactionID uint) error {
+	transaction := new(model.BTCTransaction)
+
+	if err := database.First(transaction, transactionID).Error; err != nil {
+		return err
+	}
+
+	if transaction.Status != model.TRANSACTION_STATUS_WAITING_FOR_GAS {
+		return fmt.Errorf("transaction %d isn't waiting for gas", transactionID)
+	}
+
+	return database.Model(transaction).Update("mint_is_forced", true).Error
+}
+
+// getMintTransactionHashes returns the hash of the latest mint transaction of the purchase and the ones it replaced.
+func (controller ExchangeController) getMintTransactionHashes(transaction *model.BTCTransaction) []common.Hash {
+	hashes := []common.Hash{common.HexToHash(transaction.MintTransactionHash)}
+
+	if transaction.ID == 0 {
+		return hashes
+	}
+
+	replacements := make([]model.MintReplacement, 0)
 
-	tokensAmount, _ := big.NewFloat(0).Mul(bigWei, exchangeRate).Int(nil)
+	if err := controller.database.Where("transaction_id = ?", transaction.ID).Order("id desc").Find(&replacements).Error; err != nil {
+		log.Println(err)
+	}
 
-	tokensLeft, err := controller.TokenManagementController.GetTokensLeft()
+	for _, replacement := range replacements {
+		hashes = append(hashes, common.HexToHash(replacement.ReplacedHash))
+	}
+
+	return hashes
+}
+
+// replaceMintTransaction resends the stuck mint transaction with bumped fees.
+func (controller ExchangeController) replaceMintTransaction(transaction *model.BTCTransaction) error {
+	data, err := hexutil.Decode(transaction.MintData)
 
 	if err != nil {
-		transaction.Error = err.Error()
-		transaction.Status = model.TRANSACTION_STATUS_ERROR
-		controller.database.Save(transaction)
-		return
+		return err
+	}
+
+	previous := MintTransaction{
+		From:     common.HexToAddress(transaction.MintFrom),
+		Hash:     common.HexToHash(transaction.MintTransactionHash),
+		Nonce:    transaction.MintNonce,
+		GasLimit: transaction.MintGasLimit,
+		Fees: TransactionFees{
+			Type:                 transaction.MintTransactionType,
+			GasPrice:             transaction.MintGasPrice.Big(),
+			MaxFeePerGas:         transaction.MintMaxFeePerGas.Big(),
+			MaxPriorityFeePerGas: transaction.MintMaxPriorityFeePerGas.Big(),
+		},
+		Data: data,
 	}
 
-	tokensToTransfer := tokensAmount
+	currentFees, err := controller.TokenManagementController.feeController.GetFees()
 
-	if tokensAmount.Cmp(tokensLeft) == 1 {
-		tokensToTransfer = tokensLeft
+	if err != nil {
+		return err
 	}
 
-	if err := controller.TokenManagementController.MintTokens(common.HexToAddress(transaction.EthereumAddress), tokensToTransfer); err != nil {
-		transaction.Error = err.Error()
-		transaction.Status = model.TRANSACTION_STATUS_ERROR
+	fees, err := controller.GasBumpingController.BumpFees(previous.Fees, currentFees)
+
+	if err != nil {
+		return err
+	}
+
+	mintTransaction, err := controller.TokenManagementController.ReplaceMintTransaction(previous, fees)
+
+	if err != nil {
+		return err
+	}
+
+	sentAt := time.Now()
+
+	transaction.MintTransactionHash = mintTransaction.Hash.Hex()
+	transaction.MintGasPrice = model.NewBigInt(fees.GasPrice)
+	transaction.MintMaxFeePerGas = model.NewBigInt(fees.MaxFeePerGas)
+	transaction.MintMaxPriorityFeePerGas = model.NewBigInt(fees.MaxPriorityFeePerGas)
+	transaction.MintSentAt = &sentAt
+	controller.database.Save(transaction)
+
+	return controller.database.Create(&model.MintReplacement{
+		TransactionID:        transaction.ID,
+		ReplacedHash:         previous.Hash.Hex(),
+		Hash:                 transaction.MintTransactionHash,
+		GasPrice:             transaction.MintGasPrice,
+		MaxFeePerGas:         transaction.MintMaxFeePerGas,
+		MaxPriorityFeePerGas: transaction.MintMaxPriorityFeePerGas,
+	}).Error
+}
+
+// trackMintTransaction marks the purchase successful once the mint transaction has enough confirmations.
+// The transaction is replaced while it's stuck in the pool.
+func (controller ExchangeController) trackMintTransaction(transaction *model.BTCTransaction) {
+	defer controller.TokenManagementController.ReleaseMintSigner(common.HexToAddress(transaction.MintFrom))
+
+	if transaction.MintSentAt == nil {
+		sentAt := time.Now()
+		transaction.MintSentAt = &sentAt
+	}
+
+	ticker := time.NewTicker(controller.receiptController.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		hashes := controller.getMintTransactionHashes(transaction)
+		receipt, hash, confirmations, err := controller.receiptController.CheckReceipts(hashes)
+
+		if err != nil {
+			log.Println(err)
+		}
+
+		if receipt != nil && controller.receiptController.IsConfirmed(confirmations) {
+			controller.TokenManagementController.SettleMintNonce(common.HexToAddress(transaction.MintFrom), transaction.MintNonce, true)
+			controller.completeMintTransaction(transaction, hash, receipt)
+			return
+		}
+
+		now := time.Now()
+
+		if receipt == nil && controller.isMintDropped(transaction, hashes, now) {
+			controller.TokenManagementController.SettleMintNonce(common.HexToAddress(transaction.MintFrom), transaction.MintNonce, false)
+			transaction.Error = ErrTransactionDropped.Error()
+			transaction.Status = model.TRANSACTION_STATUS_MINT_FAILED
+			controller.database.Save(transaction)
+			return
+		}
+
+		if receipt == nil && controller.GasBumpingController.IsStuck(*transaction.MintSentAt, now) {
+			if err := controller.replaceMintTransaction(transaction); err != nil {
+				log.Println("UNABLE TO REPLACE MINT TRANSACTION", transaction.MintTransactionHash, err)
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+// isMintDropped tells whether the mint transaction can be given up on without risking a second mint.
+func (controller ExchangeController) isMintDropped(transaction *model.BTCTransaction, hashes []common.Hash, now time.Time) bool {
+	account, err := controller.TokenManagementController.mintAccount(common.HexToAddress(transaction.MintFrom))
+
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	isDropped, err := controller.receiptController.IsDropped(account, transaction.MintNonce, hashes, *transaction.MintSentAt, now)
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	return isDropped
+}
+
+// completeMintTransaction records the outcome of the confirmed mint transaction, which is the latest one or one it replaced.
+func (controller ExchangeController) completeMintTransaction(transaction *model.BTCTransaction, hash common.Hash, receipt *TransactionReceipt) {
+	transaction.MintTransactionHash = hash.Hex()
+	transaction.MintBlockNumber = receipt.BlockNumber
+	transaction.MintGasUsed = receipt.GasUsed
+
+	if !receipt.IsSuccessful {
+		transaction.Error = "mint transaction reverted"
+		transaction.Status = model.TRANSACTION_STATUS_MINT_FAILED
 		controller.database.Save(transaction)
 		return
 	}
@@ -198,11 +718,24 @@ func (controller *ExchangeController) BuyTokens(transaction *model.BTCTransactio
 func (controller ExchangeController) ResumeMonitoring() {
 	unfinishedTransactions := new([]model.BTCTransaction)
 
-	if err := controller.database.Where("status = ?", model.TRANSACTON_STATUS_NEW).Find(unfinishedTransactions).Error; err != nil {
+	if err := controller.database.Where(
+		"status in (?) and bitcoin_address <> ''",
+		[]int8{model.TRANSACTON_STATUS_NEW, model.TRANSACTION_STATUS_BELOW_MINIMUM},
+	).Find(unfinishedTransactions).Error; err != nil {
+		log.Println(err)
+	}
+
+	for index := range *unfinishedTransactions {
+		go controller.BuyTokens(&(*unfinishedTransactions)[index])
+	}
+
+	mintingTransactions := new([]model.BTCTransaction)
+
+	if err := controller.database.Where("status = ?", model.TRANSACTION_STATUS_MINTING).Find(mintingTransactions).Error; err != nil {
 		log.Println(err)
 	}
 
-	for _, transaction := range *unfinishedTransactions {
-		go controller.BuyTokens(&transaction)
+	for index := range *mintingTransactions {
+		go controller.trackMintTransaction(&(*mintingTransactions)[index])
 	}
 }
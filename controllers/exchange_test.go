@@ -1,15 +1,23 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"MCW-btc-module/model"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/jarcoal/httpmock.v1"
 )
 
@@ -24,8 +32,14 @@ func TestMakeExchangeController(t *testing.T) {
 	controller, err := MakeExchangeController(
 		MonitoringController{},
 		TokenManagementController{},
+		RateOracleController{},
+		QuoteController{},
+		RateCircuitBreakerController{},
+		ReceiptController{},
+		GasBumpingController{},
 		db,
 		"tpubDAbGZM7PHnNp75QbARzDM7id7zpBcxKH9EX7VFE2Pr15EuWQEdRzSZSB4fhnHBxeLyzZB6QnhewQQhdkRHx6wCow3iTj6BXfwGsj8RevWoC",
+		true,
 	)
 
 	assert.NoError(t, err)
@@ -40,8 +54,14 @@ func TestMakeExchangeControllerError(t *testing.T) {
 	controller, err := MakeExchangeController(
 		MonitoringController{},
 		TokenManagementController{},
+		RateOracleController{},
+		QuoteController{},
+		RateCircuitBreakerController{},
+		ReceiptController{},
+		GasBumpingController{},
 		db,
 		"pubDAbGZM7PHnNp75QbARzDM7id7zpBcxKH9EX7VFE2Pr15EuWQEdRzSZSB4fhnHBxeLyzZB6QnhewQQhdkRHx6wCow3iTj6BXfwGsj8RevWoC",
+		true,
 	)
 
 	assert.Error(t, err)
@@ -53,11 +73,21 @@ func TestGetExchangeRate(t *testing.T) {
 
 	assert.NoError(t, err)
 
+	rateOracleController, err := MakeRateOracleController([]RateSource{BinanceRateSource{binanceRateEndpoint}}, 0.05, 1)
+
+	assert.NoError(t, err)
+
 	controller, err := MakeExchangeController(
 		MonitoringController{},
 		TokenManagementController{},
+		rateOracleController,
+		QuoteController{},
+		RateCircuitBreakerController{},
+		ReceiptController{},
+		GasBumpingController{},
 		db,
 		"tpubDAbGZM7PHnNp75QbARzDM7id7zpBcxKH9EX7VFE2Pr15EuWQEdRzSZSB4fhnHBxeLyzZB6QnhewQQhdkRHx6wCow3iTj6BXfwGsj8RevWoC",
+		true,
 	)
 
 	if assert.NoError(t, err) {
@@ -66,12 +96,12 @@ func TestGetExchangeRate(t *testing.T) {
 
 		httpmock.RegisterResponder(
 			http.MethodGet,
-			"https://shapeshift.io/rate/btc_eth",
+			binanceRateEndpoint,
 			func(request *http.Request) (*http.Response, error) {
 
 				return httpmock.NewStringResponse(
 					http.StatusOK,
-					`{"rate": "10"}`,
+					`{"symbol": "ETHBTC", "price": "0.1"}`,
 				), nil
 
 			},
@@ -85,78 +115,374 @@ func TestGetExchangeRate(t *testing.T) {
 }
 
 func TestExchangeController_BuyTokens(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	// Every connection to an in-memory database opens a database of its own.
+	db.DB().SetMaxOpenConns(1)
+
+	require.NoError(t, model.Migrate(db))
+	require.NoError(t, db.AutoMigrate(model.User{}).Error)
+
+	receiver := common.HexToAddress("0x123")
+
+	require.NoError(t, db.Create(&model.User{Email: "investor@example.com", EthAddr: receiver.Hex()}).Error)
+
+	infuraController := testInfuraController()
+
+	tokenManagementController, err := MakeTokenManagementController(infuraController, "", "")
+
+	require.NoError(t, err)
+
+	signer := &TransactionSigner{chainID: big.NewInt(5), accountSigner: testKeySigner()}
+
+	*tokenManagementController = tokenManagementController.WithTransactionSigner(signer)
+
+	rateOracleController, err := MakeRateOracleController([]RateSource{BinanceRateSource{binanceRateEndpoint}}, 0.05, 1)
+
+	require.NoError(t, err)
+
+	quoteController, err := MakeQuoteController("secret", time.Hour, QuotePolicyRequote)
+
+	require.NoError(t, err)
+
+	receiptController, err := MakeReceiptController(infuraController, 6, time.Millisecond, time.Hour)
+
+	require.NoError(t, err)
+
+	monitoringController := MakeMonitoringController(MakeBlockCypherController("token", true), MakeBlocktrailController("key", true))
+	monitoringController.pollInterval = time.Millisecond
+
+	controller, err := MakeExchangeController(
+		monitoringController,
+		*tokenManagementController,
+		rateOracleController,
+		quoteController,
+		RateCircuitBreakerController{},
+		receiptController,
+		GasBumpingController{},
+		db,
+		"tpubDAbGZM7PHnNp75QbARzDM7id7zpBcxKH9EX7VFE2Pr15EuWQEdRzSZSB4fhnHBxeLyzZB6QnhewQQhdkRHx6wCow3iTj6BXfwGsj8RevWoC",
+		true,
+	)
+
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		binanceRateEndpoint,
+		httpmock.NewStringResponder(http.StatusOK, `{"symbol": "ETHBTC", "price": "0.1"}`),
+	)
+
+	ether := big.NewInt(1000000000000000000)
+	results := map[string]*big.Int{}
+
+	pack := func(name string, arguments ...interface{}) string {
+		data, err := tokenManagementController.crowdsaleContractABI.Pack(name, arguments...)
+		require.NoError(t, err)
+		return hexutil.Bytes(data).String()
+	}
+
+	for _, name := range []string{"isPreIco", "preIcoTokenRate", "preIcoTokenRateNegativeDecimals", "icoTokenRateNegativeDecimals", "tokensRemainingPreIco"} {
+		results[pack(name)] = big.NewInt(0)
+	}
+
+	results[pack("isIco")] = big.NewInt(1)
+	results[pack("icoTokenRate")] = big.NewInt(2)
+	results[pack("tokensRemainingIco")] = big.NewInt(0).Mul(big.NewInt(1000), ether)
+	results[pack("MINIMAL_INVESTMENT")] = big.NewInt(0).Div(ether, big.NewInt(1000))
+	results[pack("MAXIMAL_INVESTMENT")] = big.NewInt(0).Mul(big.NewInt(5), ether)
+	results[pack("getPreIcoInvestment", receiver)] = big.NewInt(0)
+	results[pack("getIcoInvestment", receiver)] = big.NewInt(0)
+
+	var sentTransaction string
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		batchResponder(func(request *http.Request) (*http.Response, error) {
+			requestBody := new(requestPayload)
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			result := ""
+
+			switch requestBody.Method {
+			case "eth_call":
+				call := requestBody.Params[0].(map[string]interface{})
+
+				// The mint simulation passes.
+				if _, ok := call["from"]; ok {
+					result = `"0x"`
+					break
+				}
+
+				value, ok := results[call["data"].(string)]
+
+				if !ok {
+					return nil, errors.New("unexpected call")
+				}
+
+				result = `"` + common.BigToHash(value).Hex() + `"`
+			case "eth_estimateGas":
+				result = `"0x30d40"`
+			case "eth_gasPrice":
+				result = `"0x3b9aca00"`
+			case "eth_getTransactionCount":
+				result = `"0x5"`
+			case "eth_sendRawTransaction":
+				sentTransaction = requestBody.Params[0].(string)
+				result = `"0x0"`
+			case "eth_getTransactionReceipt":
+				result = `{"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x1"}`
+			case "eth_blockNumber":
+				result = `"0x20"`
+			default:
+				return nil, errors.New("unexpected method " + requestBody.Method)
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": `+result+`}`), nil
+		}),
+	)
+
+	transaction, isNew, err := controller.CreateTransactionEntry(receiver.Hex())
+
+	require.NoError(t, err)
+	require.True(t, isNew)
+
+	assert.Equal(t, float64(10), transaction.QuoteRate)
+	assert.Equal(t, "2e-0", transaction.QuoteTokenRate)
+
+	user := new(model.User)
+
+	if assert.NoError(t, db.Where("eth_addr = ?", receiver.Hex()).First(user).Error) {
+		assert.Equal(t, transaction.BitcoinAddress, user.BtcAddr)
+	}
+
+	// Quotes can't be issued anymore, so the purchase isn't started again for the next deposit.
+	httpmock.RegisterResponder(http.MethodGet, binanceRateEndpoint, httpmock.NewStringResponder(http.StatusServiceUnavailable, ""))
+
+	httpmock.RegisterResponder(http.MethodGet,
+		strings.Replace(controller.endpoint, "%address%", transaction.BitcoinAddress, 1),
+		func(request *http.Request) (*http.Response, error) {
+			return httpmock.NewJsonResponse(
+				http.StatusOK,
+				map[string]interface{}{
+					"data": []map[string]interface{}{
+						{
+							"hash":          "902912aeafe06a03ca95c70cad2e709c89e9b4f4a99aa6a0ae386408ae131b0f",
+							"time":          "2014-09-05T17:08:04+0000",
+							"confirmations": 279,
+							"is_coinbase":   false,
+							"value":         15000,
+							"index":         0,
+							"address":       "1NcXPMRaanz43b1kokpPuYDdk6GGDvxT2T",
+							"type":          "pubkeyhash",
+							"script":        "DUP HASH160 0x14 0xed12908714ffd43142bf9832692017e8ad54e9a8 EQUALVERIFY CHECKSIG",
+							"script_hex":    "76a914ed12908714ffd43142bf9832692017e8ad54e9a888ac",
+						}},
+					"current_page": 1,
+					"per_page":     20,
+					"total":        4,
+				})
+		},
+	)
+
+	controller.BuyTokens(transaction)
+
+	stored := model.BTCTransaction{}
+
+	require.NoError(t, db.First(&stored, transaction.ID).Error)
+
+	// 15000 satoshis at 10 ETH per BTC.
+	weiAmount := big.NewInt(1500000000000000)
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_SUCCESS), stored.Status, stored.Error)
+	assert.Equal(t, int64(15000), stored.SatoshisTransferred)
+	assert.Equal(t, "10", stored.ExchangeRate)
+	assert.Equal(t, "2e-0", stored.TokenRate)
+	assert.Equal(t, weiAmount, stored.AcceptedWei.Big())
+	assert.Equal(t, big.NewInt(0), stored.RefundedWei.Big())
+	assert.Equal(t, int64(0), stored.SatoshisRefunded)
+	assert.Equal(t, signer.Address().Hex(), stored.MintFrom)
+	assert.Equal(t, uint64(5), stored.MintNonce)
+	assert.Equal(t, uint64(27), stored.MintBlockNumber)
+	assert.NotEmpty(t, sentTransaction)
+
+	rates, err := controller.GetRateHistory(time.Time{}, time.Time{}, 0)
+
+	if assert.NoError(t, err) && assert.Len(t, rates, 2) {
+		assert.Equal(t, model.RATE_HISTORY_KIND_PURCHASE, rates[0].Kind)
+		assert.Equal(t, model.RATE_HISTORY_KIND_QUOTE, rates[1].Kind)
+	}
+}
+
+func TestExchangeController_CheckPurchaseRate(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	require.NoError(t, model.Migrate(db))
+
+	rateOracleController, err := MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 10, nil},
+		mockRateSource{"b", 100, nil},
+		mockRateSource{"c", 10.1, nil},
+	}, 0.05, 2)
+
+	require.NoError(t, err)
+
+	rateCircuitBreakerController, err := MakeRateCircuitBreakerController(0.1, 0.05, time.Hour)
+
+	require.NoError(t, err)
+
+	controller := ExchangeController{
+		RateOracleController:         rateOracleController,
+		RateCircuitBreakerController: rateCircuitBreakerController,
+		database:                     db,
+	}
+
+	now := time.Now()
+
+	// The source 10x off is rejected by the oracle, so sales go on.
+	assert.NoError(t, controller.checkPurchaseRate(10.05, now))
+	assert.EqualError(t, controller.checkPurchaseRate(11, now), "rate 11.000000 deviates from median rate 10.050000")
+	assert.EqualError(t, controller.checkPurchaseRate(10.05, now.Add(-2*time.Hour)), fmt.Sprintf("rate 10.050000 from %s is stale", now.Add(-2*time.Hour).UTC()))
+}
+
+func TestExchangeController_GetRateHistory(t *testing.T) {
 	db, err := gorm.Open("sqlite3", "test.db")
 
 	assert.NoError(t, err)
 
+	db.DropTableIfExists(model.RateHistory{})
+	db.AutoMigrate(model.RateHistory{})
+
 	controller, err := MakeExchangeController(
 		MonitoringController{},
 		TokenManagementController{},
+		RateOracleController{},
+		QuoteController{},
+		RateCircuitBreakerController{},
+		ReceiptController{},
+		GasBumpingController{},
 		db,
 		"tpubDAbGZM7PHnNp75QbARzDM7id7zpBcxKH9EX7VFE2Pr15EuWQEdRzSZSB4fhnHBxeLyzZB6QnhewQQhdkRHx6wCow3iTj6BXfwGsj8RevWoC",
+		true,
 	)
 
 	if assert.NoError(t, err) {
-		httpmock.Activate()
-		defer httpmock.DeactivateAndReset()
+		transaction := &model.BTCTransaction{ID: 7}
 
-		httpmock.RegisterResponder(
-			http.MethodGet,
-			"https://shapeshift.io/rate/btc_eth",
-			func(request *http.Request) (*http.Response, error) {
+		controller.recordRate(transaction, model.RATE_HISTORY_KIND_QUOTE, 10.5, "1000")
+		controller.recordRate(transaction, model.RATE_HISTORY_KIND_PURCHASE, 10.25, "1000")
 
-				return httpmock.NewStringResponse(
-					http.StatusOK,
-					`{"rate": "10"}`,
-				), nil
+		records, err := controller.GetRateHistory(time.Time{}, time.Time{}, 0)
 
-			},
-		)
-		httpmock.RegisterResponder(
-			http.MethodPost,
-			controller.infuraEndpoint,
-			func(request *http.Request) (*http.Response, error) {
+		if assert.NoError(t, err) && assert.Len(t, records, 2) {
+			assert.Equal(t, uint(7), records[0].TransactionID)
+			assert.Equal(t, model.RATE_HISTORY_KIND_PURCHASE, records[0].Kind)
+			assert.Equal(t, "10.25", records[0].ExchangeRate)
+			assert.Equal(t, "10.5", records[1].ExchangeRate)
+			assert.Equal(t, "1000", records[1].TokenRate)
+		}
 
-				return httpmock.NewStringResponse(
-					http.StatusOK,
-					`{"jsonrpc": "2.0", "result": "0x1"}`,
-				), nil
+		records, err = controller.GetRateHistory(time.Time{}, time.Time{}, 1)
 
-			},
-		)
-
-		transaction, isNew, err := controller.CreateTransactionEntry("")
+		if assert.NoError(t, err) {
+			assert.Len(t, records, 1)
+		}
 
-		httpmock.RegisterResponder(http.MethodGet,
-			strings.Replace(controller.endpoint, "%address%", transaction.BitcoinAddress, 1),
-			func(request *http.Request) (*http.Response, error) {
-				return httpmock.NewJsonResponse(
-					http.StatusOK,
-					map[string]interface{}{
-						"data": []map[string]interface{}{
-							{
-								"hash":          "902912aeafe06a03ca95c70cad2e709c89e9b4f4a99aa6a0ae386408ae131b0f",
-								"time":          "2014-09-05T17:08:04+0000",
-								"confirmations": 279,
-								"is_coinbase":   false,
-								"value":         15000,
-								"index":         0,
-								"address":       "1NcXPMRaanz43b1kokpPuYDdk6GGDvxT2T",
-								"type":          "pubkeyhash",
-								"script":        "DUP HASH160 0x14 0xed12908714ffd43142bf9832692017e8ad54e9a8 EQUALVERIFY CHECKSIG",
-								"script_hex":    "76a914ed12908714ffd43142bf9832692017e8ad54e9a888ac",
-							}},
-						"current_page": 1,
-						"per_page":     20,
-						"total":        4,
-					})
-			},
-		)
+		records, err = controller.GetRateHistory(time.Now().Add(time.Hour), time.Time{}, 0)
 
 		if assert.NoError(t, err) {
-			assert.True(t, isNew)
-			controller.BuyTokens(transaction)
-
+			assert.Len(t, records, 0)
 		}
 	}
 }
+
+func TestExchangeController_MintWaitingPurchases(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.DropTableIfExists(model.BTCTransaction{})
+	db.AutoMigrate(model.BTCTransaction{})
+
+	infuraController := testInfuraController()
+
+	tokenManagementController, err := MakeTokenManagementController(infuraController, "", "")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// The network asks 1 gwei, twice the ceiling.
+	*tokenManagementController = tokenManagementController.
+		WithTransactionSigner(&TransactionSigner{chainID: big.NewInt(5), accountSigner: testKeySigner()}).
+		WithFeeCeiling(big.NewInt(500000000))
+
+	controller := ExchangeController{
+		TokenManagementController: *tokenManagementController,
+		database:                  db,
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Every crowdsale call answers true, i.e. 1, so the crowdsale is in pre-ICO and the simulation passes.
+	registerFakeNode(infuraController, map[string]string{
+		"eth_call":                `"0x0000000000000000000000000000000000000000000000000000000000000001"`,
+		"eth_estimateGas":         `"0x30d40"`,
+		"eth_gasPrice":            `"0x3b9aca00"`,
+		"eth_getTransactionCount": `"0x5"`,
+		"eth_sendRawTransaction":  `"0x0"`,
+	})
+
+	transactions := []*model.BTCTransaction{
+		{EthereumAddress: "0x123", AcceptedWei: model.NewBigInt(big.NewInt(1000)), Status: model.TRANSACTION_STATUS_WAITING_FOR_GAS},
+		{EthereumAddress: "0x456", AcceptedWei: model.NewBigInt(big.NewInt(1000)), Status: model.TRANSACTION_STATUS_SUCCESS},
+	}
+
+	for _, transaction := range transactions {
+		db.Create(transaction)
+	}
+
+	controller.mintWaitingPurchases()
+
+	waiting := new(model.BTCTransaction)
+	db.First(waiting, transactions[0].ID)
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_WAITING_FOR_GAS), waiting.Status)
+	assert.Equal(t, "", waiting.MintTransactionHash)
+
+	assert.EqualError(t, ForceMint(db, transactions[1].ID), fmt.Sprintf("transaction %d isn't waiting for gas", transactions[1].ID))
+	assert.NoError(t, ForceMint(db, transactions[0].ID))
+
+	db.First(waiting, transactions[0].ID)
+
+	if assert.True(t, waiting.MintIsForced) && assert.True(t, controller.mintPurchase(waiting)) {
+		assert.Equal(t, int8(model.TRANSACTION_STATUS_MINTING), waiting.Status)
+		assert.Equal(t, uint64(5), waiting.MintNonce)
+		assert.Equal(t, testKeySigner().Address().Hex(), waiting.MintFrom)
+	}
+
+	// Without the ceiling the purchases mint right away.
+	controller.TokenManagementController = controller.TokenManagementController.WithFeeCeiling(big.NewInt(0))
+
+	transaction := &model.BTCTransaction{EthereumAddress: "0x789", AcceptedWei: model.NewBigInt(big.NewInt(1000))}
+
+	assert.True(t, controller.mintPurchase(transaction))
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	feeHistoryBlocks           = 10
+	feeHistoryRewardPercentile = 50
+)
+
+// TransactionFees are the gas settings of a transaction. Legacy transactions only use GasPrice.
+type TransactionFees struct {
+	Type                 int8
+	GasPrice             *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	// NetworkFeePerGas is what the network asked per gas when the fees were priced, before the caps.
+	// It's the base fee plus the tip, or the gas price before London.
+	NetworkFeePerGas *big.Int
+}
+
+// MaxCost is the most a transaction using gasLimit gas may pay for it.
+func (fees TransactionFees) MaxCost(gasLimit uint64) *big.Int {
+	feePerGas := fees.GasPrice
+
+	if fees.Type == DynamicFeeTransactionType {
+		feePerGas = fees.MaxFeePerGas
+	}
+
+	return new(big.Int).Mul(feePerGas, new(big.Int).SetUint64(gasLimit))
+}
+
+// FeeController prices transactions. The zero caps are not applied.
+type FeeController struct {
+	InfuraController
+	isDynamicFeeEnabled     bool
+	maxFeePerGasCap         *big.Int
+	maxPriorityFeePerGasCap *big.Int
+}
+
+func MakeFeeController(
+	infuraController InfuraController,
+	isDynamicFeeEnabled bool,
+	maxFeePerGasCap *big.Int,
+	maxPriorityFeePerGasCap *big.Int,
+) (FeeController, error) {
+	if (maxFeePerGasCap != nil && maxFeePerGasCap.Sign() == -1) || (maxPriorityFeePerGasCap != nil && maxPriorityFeePerGasCap.Sign() == -1) {
+		return FeeController{}, errors.New("fee caps must not be negative")
+	}
+
+	return FeeController{
+		InfuraController:        infuraController,
+		isDynamicFeeEnabled:     isDynamicFeeEnabled,
+		maxFeePerGasCap:         maxFeePerGasCap,
+		maxPriorityFeePerGasCap: maxPriorityFeePerGasCap,
+	}, nil
+}
+
+type feeHistory struct {
+	BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas"`
+	Reward        [][]*hexutil.Big `json:"reward"`
+}
+
+// methodNotFoundErrorCode is the JSON-RPC error code of methods the node doesn't know.
+const methodNotFoundErrorCode = -32601
+
+// getFeeHistory returns nil if the network doesn't support EIP-1559.
+func (controller FeeController) getFeeHistory() (*feeHistory, error) {
+	response := new(infuraRawResponse)
+
+	err := controller.InfuraController.postInfura(makeRequestPayload(
+		"eth_feeHistory",
+		[]interface{}{hexutil.EncodeUint64(feeHistoryBlocks), "latest", []int{feeHistoryRewardPercentile}},
+	), response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Nodes before London don't know the method.
+	if response.Error != nil && response.Error.Code == methodNotFoundErrorCode {
+		return nil, nil
+	}
+
+	if response.Error != nil {
+		return nil, errors.New(response.Error.Message)
+	}
+
+	history := new(feeHistory)
+
+	if err := json.Unmarshal(response.Result, history); err != nil {
+		return nil, err
+	}
+
+	// Blocks before London have no base fee.
+	if len(history.BaseFeePerGas) == 0 || history.BaseFeePerGas[len(history.BaseFeePerGas)-1] == nil ||
+		history.BaseFeePerGas[len(history.BaseFeePerGas)-1].ToInt().Sign() == 0 {
+		return nil, nil
+	}
+
+	return history, nil
+}
+
+func (controller FeeController) getMaxPriorityFeePerGas(history *feeHistory) (*big.Int, error) {
+	response, err := controller.InfuraController.callInfura(makeRequestPayload("eth_maxPriorityFeePerGas", []interface{}{}))
+
+	if err == nil {
+		return hexutil.DecodeBig(response.Result)
+	}
+
+	rewards := make([]*big.Int, 0, len(history.Reward))
+
+	for _, blockRewards := range history.Reward {
+		if len(blockRewards) > 0 && blockRewards[0] != nil {
+			rewards = append(rewards, blockRewards[0].ToInt())
+		}
+	}
+
+	if len(rewards) == 0 {
+		return nil, err
+	}
+
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) == -1 })
+
+	return rewards[len(rewards)/2], nil
+}
+
+func (controller FeeController) getGasPrice() (*big.Int, error) {
+	response, err := controller.InfuraController.callInfura(makeRequestPayload("eth_gasPrice", []interface{}{}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hexutil.DecodeBig(response.Result)
+}
+
+func capFee(fee *big.Int, feeCap *big.Int) *big.Int {
+	if feeCap != nil && feeCap.Sign() == 1 && fee.Cmp(feeCap) == 1 {
+		return new(big.Int).Set(feeCap)
+	}
+
+	return fee
+}
+
+// GetFees prices the transaction as EIP-1559 transaction if enabled and supported by the network, and as legacy one otherwise.
+func (controller FeeController) GetFees() (*TransactionFees, error) {
+	if controller.isDynamicFeeEnabled {
+		history, err := controller.getFeeHistory()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if history != nil {
+			baseFee := history.BaseFeePerGas[len(history.BaseFeePerGas)-1].ToInt()
+
+			priorityFee, err := controller.getMaxPriorityFeePerGas(history)
+
+			if err != nil {
+				return nil, err
+			}
+
+			networkFee := new(big.Int).Add(baseFee, priorityFee)
+
+			priorityFee = capFee(priorityFee, controller.maxPriorityFeePerGasCap)
+
+			// Twice the base fee keeps the transaction includable through several full blocks.
+			maxFee := new(big.Int).Mul(baseFee, big.NewInt(2))
+			maxFee = capFee(maxFee.Add(maxFee, priorityFee), controller.maxFeePerGasCap)
+
+			if priorityFee.Cmp(maxFee) == 1 {
+				priorityFee = new(big.Int).Set(maxFee)
+			}
+
+			return &TransactionFees{
+				Type:                 DynamicFeeTransactionType,
+				MaxFeePerGas:         maxFee,
+				MaxPriorityFeePerGas: priorityFee,
+				NetworkFeePerGas:     networkFee,
+			}, nil
+		}
+	}
+
+	gasPrice, err := controller.getGasPrice()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionFees{
+		Type:             LegacyTransactionType,
+		GasPrice:         capFee(gasPrice, controller.maxFeePerGasCap),
+		NetworkFeePerGas: gasPrice,
+	}, nil
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func registerFakeNode(controller InfuraController, results map[string]string) {
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		batchResponder(func(request *http.Request) (*http.Response, error) {
+			defer request.Body.Close()
+
+			requestBodyBytes, err := ioutil.ReadAll(request.Body)
+
+			if err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			requestBody := new(requestPayload)
+
+			if err := json.Unmarshal(requestBodyBytes, requestBody); err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			result, ok := results[requestBody.Method]
+
+			if !ok {
+				return httpmock.NewStringResponse(
+					http.StatusOK,
+					`{"jsonrpc": "2.0", "error": {"code": -32601, "message": "the method `+requestBody.Method+` does not exist/is not available"}}`,
+				), nil
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": `+result+`}`), nil
+		}),
+	)
+}
+
+func TestTransactionFees_MaxCost(t *testing.T) {
+	fees := TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(30), MaxPriorityFeePerGas: big.NewInt(2)}
+
+	assert.Equal(t, big.NewInt(6300000), fees.MaxCost(210000))
+
+	fees = TransactionFees{Type: LegacyTransactionType, GasPrice: big.NewInt(20)}
+
+	assert.Equal(t, big.NewInt(4200000), fees.MaxCost(210000))
+}
+
+func TestFeeController_GetFees(t *testing.T) {
+	infuraController := testInfuraController()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_feeHistory":           `{"oldestBlock": "0x10", "baseFeePerGas": ["0x3b9aca00", "0x77359400"], "reward": [["0x3b9aca00"]]}`,
+		"eth_maxPriorityFeePerGas": `"0x59682f00"`,
+		"eth_gasPrice":             `"0x4a817c800"`,
+	})
+
+	controller, err := MakeFeeController(infuraController, true, big.NewInt(0), big.NewInt(0))
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	fees, err := controller.GetFees()
+
+	// Twice the 2 gwei base fee of the next block plus the 1.5 gwei tip.
+	if assert.NoError(t, err) {
+		assert.Equal(t, int8(DynamicFeeTransactionType), fees.Type)
+		assert.Equal(t, big.NewInt(5500000000), fees.MaxFeePerGas)
+		assert.Equal(t, big.NewInt(1500000000), fees.MaxPriorityFeePerGas)
+		assert.Equal(t, big.NewInt(3500000000), fees.NetworkFeePerGas)
+		assert.Nil(t, fees.GasPrice)
+	}
+
+	controller, _ = MakeFeeController(infuraController, true, big.NewInt(3000000000), big.NewInt(1000000000))
+
+	fees, err = controller.GetFees()
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, big.NewInt(3000000000), fees.MaxFeePerGas)
+		assert.Equal(t, big.NewInt(1000000000), fees.MaxPriorityFeePerGas)
+		assert.Equal(t, big.NewInt(3500000000), fees.NetworkFeePerGas)
+	}
+
+	controller, _ = MakeFeeController(infuraController, false, big.NewInt(0), big.NewInt(0))
+
+	fees, err = controller.GetFees()
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, int8(LegacyTransactionType), fees.Type)
+		assert.Equal(t, big.NewInt(20000000000), fees.GasPrice)
+	}
+
+	_, err = MakeFeeController(infuraController, false, big.NewInt(-1), big.NewInt(0))
+
+	assert.Error(t, err)
+}
+
+func TestFeeController_GetFees_BeforeLondon(t *testing.T) {
+	infuraController := testInfuraController()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_gasPrice": `"0x4a817c800"`,
+	})
+
+	controller, _ := MakeFeeController(infuraController, true, big.NewInt(10000000000), big.NewInt(0))
+
+	fees, err := controller.GetFees()
+
+	// The legacy gas price is capped as well.
+	if assert.NoError(t, err) {
+		assert.Equal(t, int8(LegacyTransactionType), fees.Type)
+		assert.Equal(t, big.NewInt(10000000000), fees.GasPrice)
+		assert.Equal(t, big.NewInt(20000000000), fees.NetworkFeePerGas)
+	}
+}
+
+func TestFeeController_GetFees_FeeHistoryFail(t *testing.T) {
+	infuraController := testInfuraController()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	controller, _ := MakeFeeController(infuraController, true, big.NewInt(0), big.NewInt(0))
+
+	// Only unknown methods mean the network is before London.
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		httpmock.NewStringResponder(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "header not found"}}`),
+	)
+
+	_, err := controller.GetFees()
+
+	assert.EqualError(t, err, "header not found")
+
+	httpmock.RegisterResponder(http.MethodPost, testRPCEndpoint, httpmock.NewStringResponder(http.StatusServiceUnavailable, ""))
+
+	_, err = controller.GetFees()
+
+	assert.EqualError(t, err, "endpoint responded with status 503")
+}
+
+func TestFeeController_GetFees_FeeHistoryReward(t *testing.T) {
+	infuraController := testInfuraController()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_feeHistory": `{"oldestBlock": "0x10", "baseFeePerGas": ["0x3b9aca00", "0x3b9aca00", "0x3b9aca00"], "reward": [["0x1"], ["0x3"]]}`,
+	})
+
+	controller, _ := MakeFeeController(infuraController, true, big.NewInt(0), big.NewInt(0))
+
+	fees, err := controller.GetFees()
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, big.NewInt(3), fees.MaxPriorityFeePerGas)
+		assert.Equal(t, big.NewInt(2000000003), fees.MaxFeePerGas)
+	}
+}
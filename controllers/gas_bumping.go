@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+var ErrFeeCeilingReached = errors.New("replacement fees would exceed the fee ceiling")
+
+// minimalFeeBumpPercent is the least fee increase nodes accept for a replacement transaction.
+const minimalFeeBumpPercent = 10
+
+// GasBumpingController replaces mint transactions pending for too long with ones paying more.
+// Transactions aren't replaced if stuckAfter is zero.
+type GasBumpingController struct {
+	stuckAfter   time.Duration
+	bumpPercent  int64
+	maxFeePerGas *big.Int
+}
+
+func MakeGasBumpingController(stuckAfter time.Duration, bumpPercent int64, maxFeePerGas *big.Int) (GasBumpingController, error) {
+	if stuckAfter < 0 {
+		return GasBumpingController{}, errors.New("stuck transaction threshold must not be negative")
+	}
+
+	if stuckAfter > 0 && bumpPercent < minimalFeeBumpPercent {
+		return GasBumpingController{}, errors.New("fees must be bumped by at least 10 percent")
+	}
+
+	if stuckAfter > 0 && (maxFeePerGas == nil || maxFeePerGas.Sign() != 1) {
+		return GasBumpingController{}, errors.New("fee ceiling must be positive")
+	}
+
+	return GasBumpingController{
+		stuckAfter:   stuckAfter,
+		bumpPercent:  bumpPercent,
+		maxFeePerGas: maxFeePerGas,
+	}, nil
+}
+
+func (controller GasBumpingController) IsStuck(sentAt time.Time, now time.Time) bool {
+	return controller.stuckAfter > 0 && now.Sub(sentAt) > controller.stuckAfter
+}
+
+// bump rounds up, so that small fees still grow.
+func (controller GasBumpingController) bump(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+controller.bumpPercent))
+	bumped.Add(bumped, big.NewInt(99))
+
+	return bumped.Quo(bumped, big.NewInt(100))
+}
+
+func maxBig(x *big.Int, y *big.Int) *big.Int {
+	if y != nil && y.Cmp(x) == 1 {
+		return y
+	}
+
+	return x
+}
+
+// BumpFees prices the replacement of the transaction paying the previous fees. The replacement pays the current
+// network fees if they are higher than the bumped ones, but never more than the fee ceiling.
+func (controller GasBumpingController) BumpFees(previous TransactionFees, current *TransactionFees) (*TransactionFees, error) {
+	if previous.Type == DynamicFeeTransactionType {
+		maxFee := maxBig(controller.bump(previous.MaxFeePerGas), current.MaxFeePerGas)
+		priorityFee := maxBig(controller.bump(previous.MaxPriorityFeePerGas), current.MaxPriorityFeePerGas)
+
+		if maxFee.Cmp(controller.maxFeePerGas) == 1 {
+			maxFee = new(big.Int).Set(controller.maxFeePerGas)
+		}
+
+		if maxFee.Cmp(controller.bump(previous.MaxFeePerGas)) == -1 {
+			return nil, ErrFeeCeilingReached
+		}
+
+		if priorityFee.Cmp(maxFee) == 1 {
+			priorityFee = new(big.Int).Set(maxFee)
+		}
+
+		if priorityFee.Cmp(controller.bump(previous.MaxPriorityFeePerGas)) == -1 {
+			return nil, ErrFeeCeilingReached
+		}
+
+		return &TransactionFees{
+			Type:                 DynamicFeeTransactionType,
+			MaxFeePerGas:         maxFee,
+			MaxPriorityFeePerGas: priorityFee,
+		}, nil
+	}
+
+	// Legacy transactions are replaced by legacy ones, priced at the current max fee if the network has moved on.
+	currentGasPrice := current.GasPrice
+
+	if currentGasPrice == nil {
+		currentGasPrice = current.MaxFeePerGas
+	}
+
+	gasPrice := maxBig(controller.bump(previous.GasPrice), currentGasPrice)
+
+	if gasPrice.Cmp(controller.maxFeePerGas) == 1 {
+		gasPrice = new(big.Int).Set(controller.maxFeePerGas)
+	}
+
+	if gasPrice.Cmp(controller.bump(previous.GasPrice)) == -1 {
+		return nil, ErrFeeCeilingReached
+	}
+
+	return &TransactionFees{
+		Type:     LegacyTransactionType,
+		GasPrice: gasPrice,
+	}, nil
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"MCW-btc-module/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func TestMakeGasBumpingController(t *testing.T) {
+	_, err := MakeGasBumpingController(time.Minute, 15, big.NewInt(500000000000))
+
+	assert.NoError(t, err)
+
+	_, err = MakeGasBumpingController(0, 0, nil)
+
+	assert.NoError(t, err)
+
+	_, err = MakeGasBumpingController(time.Minute, 5, big.NewInt(500000000000))
+
+	assert.EqualError(t, err, "fees must be bumped by at least 10 percent")
+
+	_, err = MakeGasBumpingController(time.Minute, 15, big.NewInt(0))
+
+	assert.EqualError(t, err, "fee ceiling must be positive")
+}
+
+func TestGasBumpingController_IsStuck(t *testing.T) {
+	controller, _ := MakeGasBumpingController(10*time.Minute, 15, big.NewInt(500000000000))
+
+	now := time.Now()
+
+	assert.False(t, controller.IsStuck(now, now.Add(time.Minute)))
+	assert.True(t, controller.IsStuck(now, now.Add(time.Hour)))
+
+	assert.False(t, GasBumpingController{}.IsStuck(now, now.Add(time.Hour)))
+}
+
+func TestGasBumpingController_BumpFees(t *testing.T) {
+	controller, _ := MakeGasBumpingController(time.Minute, 15, big.NewInt(100))
+
+	previous := TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(40), MaxPriorityFeePerGas: big.NewInt(10)}
+
+	fees, err := controller.BumpFees(previous, &TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(30), MaxPriorityFeePerGas: big.NewInt(2)})
+
+	// The priority fee is rounded up from 11.5.
+	if assert.NoError(t, err) {
+		assert.Equal(t, &TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(46), MaxPriorityFeePerGas: big.NewInt(12)}, fees)
+	}
+
+	fees, err = controller.BumpFees(previous, &TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(80), MaxPriorityFeePerGas: big.NewInt(20)})
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, &TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(80), MaxPriorityFeePerGas: big.NewInt(20)}, fees)
+	}
+
+	fees, err = controller.BumpFees(previous, &TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(300), MaxPriorityFeePerGas: big.NewInt(20)})
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, &TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(100), MaxPriorityFeePerGas: big.NewInt(20)}, fees)
+	}
+
+	_, err = controller.BumpFees(
+		TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(90), MaxPriorityFeePerGas: big.NewInt(10)},
+		&TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(30), MaxPriorityFeePerGas: big.NewInt(2)},
+	)
+
+	assert.Equal(t, ErrFeeCeilingReached, err)
+
+	fees, err = controller.BumpFees(
+		TransactionFees{Type: LegacyTransactionType, GasPrice: big.NewInt(40)},
+		&TransactionFees{Type: DynamicFeeTransactionType, MaxFeePerGas: big.NewInt(50), MaxPriorityFeePerGas: big.NewInt(2)},
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, &TransactionFees{Type: LegacyTransactionType, GasPrice: big.NewInt(50)}, fees)
+	}
+
+	_, err = controller.BumpFees(
+		TransactionFees{Type: LegacyTransactionType, GasPrice: big.NewInt(90)},
+		&TransactionFees{Type: LegacyTransactionType, GasPrice: big.NewInt(50)},
+	)
+
+	assert.Equal(t, ErrFeeCeilingReached, err)
+}
+
+func TestExchangeController_ReplaceMintTransaction(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.DropTableIfExists(model.BTCTransaction{}, model.MintReplacement{})
+	db.AutoMigrate(model.BTCTransaction{}, model.MintReplacement{})
+
+	infuraController := testInfuraController()
+
+	tokenManagementController, err := MakeTokenManagementController(infuraController, "", "")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	feeController, _ := MakeFeeController(infuraController, true, big.NewInt(0), big.NewInt(0))
+
+	*tokenManagementController = tokenManagementController.
+		WithTransactionSigner(&TransactionSigner{
+			chainID:       big.NewInt(5),
+			accountSigner: testKeySigner(),
+		}).
+		WithFeeController(feeController)
+
+	receiptController, _ := MakeReceiptController(infuraController, 6, time.Millisecond, 30*time.Millisecond)
+	gasBumpingController, _ := MakeGasBumpingController(time.Nanosecond, 15, big.NewInt(100000000000))
+
+	controller := ExchangeController{
+		TokenManagementController: *tokenManagementController,
+		GasBumpingController:      gasBumpingController,
+		receiptController:         receiptController,
+		database:                  db,
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `null`,
+		"eth_blockNumber":           `"0x20"`,
+		"eth_feeHistory":            `{"oldestBlock": "0x10", "baseFeePerGas": ["0x3b9aca00", "0x3b9aca00"], "reward": [["0x3b9aca00"]]}`,
+		"eth_maxPriorityFeePerGas":  `"0x3b9aca00"`,
+		"eth_sendRawTransaction":    `"0x0"`,
+		"eth_getTransactionByHash":  `null`,
+		"eth_getTransactionCount":   `"0x7"`,
+	})
+
+	sentAt := time.Now()
+
+	transaction := &model.BTCTransaction{
+		MintTransactionType:      DynamicFeeTransactionType,
+		MintTransactionHash:      testMintTransactionHash,
+		MintNonce:                7,
+		MintGasLimit:             210000,
+		MintData:                 "0x010203",
+		MintMaxFeePerGas:         model.NewBigInt(big.NewInt(3000000000)),
+		MintMaxPriorityFeePerGas: model.NewBigInt(big.NewInt(1000000000)),
+		MintSentAt:               &sentAt,
+		Status:                   model.TRANSACTION_STATUS_MINTING,
+	}
+
+	db.Save(transaction)
+
+	if !assert.NoError(t, controller.replaceMintTransaction(transaction)) {
+		t.FailNow()
+	}
+
+	assert.NotEqual(t, testMintTransactionHash, transaction.MintTransactionHash)
+	assert.Equal(t, "3450000000", transaction.MintMaxFeePerGas.String())
+	assert.Equal(t, "1150000000", transaction.MintMaxPriorityFeePerGas.String())
+
+	replacement := model.MintReplacement{}
+
+	if assert.NoError(t, db.Where("transaction_id = ?", transaction.ID).First(&replacement).Error) {
+		assert.Equal(t, common.HexToHash(testMintTransactionHash).Hex(), replacement.ReplacedHash)
+		assert.Equal(t, transaction.MintTransactionHash, replacement.Hash)
+	}
+
+	assert.Equal(
+		t,
+		[]common.Hash{common.HexToHash(transaction.MintTransactionHash), common.HexToHash(testMintTransactionHash)},
+		controller.getMintTransactionHashes(transaction),
+	)
+
+	// The transaction keeps being replaced until it times out, the node has forgotten it and its nonce is unused.
+	controller.trackMintTransaction(transaction)
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_MINT_FAILED), transaction.Status)
+	assert.True(t, len(controller.getMintTransactionHashes(transaction)) > 2)
+
+	// The replacements have the same nonce, so the original transaction may be mined instead.
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		func(request *http.Request) (*http.Response, error) {
+			requestBody := new(requestPayload)
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			if requestBody.Method == "eth_blockNumber" {
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "0x20"}`), nil
+			}
+
+			if requestBody.Params[0] == common.HexToHash(testMintTransactionHash).Hex() {
+				return httpmock.NewStringResponse(
+					http.StatusOK,
+					`{"jsonrpc": "2.0", "result": {"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x1"}}`,
+				), nil
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": null}`), nil
+		},
+	)
+
+	transaction.Status = model.TRANSACTION_STATUS_MINTING
+	controller.trackMintTransaction(transaction)
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_SUCCESS), transaction.Status)
+	assert.Equal(t, common.HexToHash(testMintTransactionHash).Hex(), transaction.MintTransactionHash)
+}
@@ -3,8 +3,8 @@ package controllers
 import (
 	"encoding/json"
 	"errors"
-
-	"MCW-btc-module/helpers"
+	"fmt"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
@@ -12,25 +12,34 @@ import (
 )
 
 const (
-	mainnetInfuraEndpoint = "https://mainnet.infura.io/"
-	testnetInfuraEndpoint = "https://rinkeby.infura.io/"
+	mainnetInfuraEndpoint = "https://mainnet.infura.io/v3/"
+	testnetInfuraEndpoint = "https://sepolia.infura.io/v3/"
 )
 
+// InfuraController calls the JSON-RPC API of any Ethereum node or provider, Infura being just one of them.
 type InfuraController struct {
-	infuraEndpoint string
+	client *RPCClient
 }
 
-func MakeInfuraController(accessToken string, isTestnet bool) InfuraController {
+// MakeRPCController fails over to the next endpoint whenever the current one is unreachable.
+func MakeRPCController(endpoints []RPCEndpoint) (InfuraController, error) {
+	client, err := MakeRPCClient(endpoints)
+
+	if err != nil {
+		return InfuraController{}, err
+	}
+
+	return InfuraController{client}, nil
+}
 
+func MakeInfuraEndpoint(accessToken string, isTestnet bool) RPCEndpoint {
 	endpoint := mainnetInfuraEndpoint
 
 	if isTestnet {
 		endpoint = testnetInfuraEndpoint
 	}
-	endpoint += accessToken
-	return InfuraController{
-		endpoint,
-	}
+
+	return RPCEndpoint{URL: endpoint + accessToken}
 }
 
 type (
@@ -41,9 +50,17 @@ type (
 		Error   *infuraError `json:"error"`
 	}
 
+	infuraRawResponse struct {
+		ID      int64           `json:"id"`
+		JsonRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result"`
+		Error   *infuraError    `json:"error"`
+	}
+
 	infuraError struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
 	}
 
 	requestPayload struct {
@@ -92,47 +109,211 @@ func makeEstimateGasRequestPayload(targetContract common.Address, data []byte) r
 	)
 }
 
-func (controller InfuraController) callInfura(payload requestPayload) (*infuraResponse, error) {
+func (controller InfuraController) postInfura(payload interface{}, response interface{}) error {
+	if controller.client == nil {
+		return errors.New("RPC endpoints are not set")
+	}
 
-	marshaledPayload, err := json.Marshal(payload)
+	return controller.client.post(payload, response)
+}
 
-	if err != nil {
+func (controller InfuraController) callInfura(payload requestPayload) (*infuraResponse, error) {
+	response := new(infuraResponse)
+
+	if err := controller.postInfura(payload, response); err != nil {
 		return nil, err
 	}
 
-	_, responseBody, err := helpers.Post(controller.infuraEndpoint, helpers.Headers{"Content-Type": "application/json"}, marshaledPayload)
+	if response.Error != nil {
+		return nil, errors.New(response.Error.Message)
+	}
+
+	return response, nil
+}
 
-	if err != nil {
+// callInfuraRaw is used for methods returning objects rather than strings.
+func (controller InfuraController) callInfuraRaw(payload requestPayload) (json.RawMessage, error) {
+	response := new(infuraRawResponse)
+
+	if err := controller.postInfura(payload, response); err != nil {
 		return nil, err
 	}
 
-	response := new(infuraResponse)
+	if response.Error != nil {
+		return nil, errors.New(response.Error.Message)
+	}
+
+	return response.Result, nil
+}
+
+// callInfuraBatch sends the requests as one JSON-RPC batch. The responses are in the order of the requests,
+// each carrying its own error.
+func (controller InfuraController) callInfuraBatch(payloads []requestPayload) ([]infuraRawResponse, error) {
+	batch := make([]requestPayload, len(payloads))
+
+	for index, payload := range payloads {
+		payload.ID = index + 1
+		batch[index] = payload
+	}
+
+	responses := make([]infuraRawResponse, 0, len(payloads))
 
-	if err := json.Unmarshal(responseBody, response); err != nil {
+	if err := controller.postInfura(batch, &responses); err != nil {
 		return nil, err
 	}
 
+	orderedResponses := make([]infuraRawResponse, len(payloads))
+	isAnswered := make([]bool, len(payloads))
+
+	for _, response := range responses {
+		if index := int(response.ID) - 1; index >= 0 && index < len(payloads) {
+			orderedResponses[index] = response
+			isAnswered[index] = true
+		}
+	}
+
+	for index := range payloads {
+		if !isAnswered[index] {
+			return nil, fmt.Errorf("batch response lacks %s", payloads[index].Method)
+		}
+	}
+
+	return orderedResponses, nil
+}
+
+func (response infuraRawResponse) err() error {
 	if response.Error != nil {
-		return nil, errors.New(response.Error.Message)
+		return errors.New(response.Error.Message)
 	}
 
-	return response, nil
+	return nil
 }
-func (controller InfuraController) retrieveParameter(name string, abi abi.ABI, targetContract common.Address) (*common.Address, error) {
-	packedData, err := abi.Pack(name)
+
+// contractCall is a constant contract function call of a batch, decoded into result.
+type contractCall struct {
+	result    interface{}
+	name      string
+	arguments []interface{}
+}
+
+func makeContractCall(result interface{}, name string, arguments ...interface{}) contractCall {
+	return contractCall{result, name, arguments}
+}
+
+// retrieveParameters makes the calls in one batch and unpacks their results into the declared types.
+func (controller InfuraController) retrieveParameters(abi abi.ABI, targetContract common.Address, calls []contractCall) error {
+	payloads := make([]requestPayload, len(calls))
+
+	for index, call := range calls {
+		packedData, err := abi.Pack(call.name, call.arguments...)
+
+		if err != nil {
+			return err
+		}
+
+		payloads[index] = makeCallRequestPayload(targetContract, packedData)
+	}
+
+	responses, err := controller.callInfuraBatch(payloads)
+
+	if err != nil {
+		return err
+	}
+
+	for index, response := range responses {
+		if err := response.err(); err != nil {
+			return err
+		}
+
+		var result hexutil.Bytes
+
+		if err := json.Unmarshal(response.Result, &result); err != nil {
+			return err
+		}
+
+		if err := abi.Unpack(calls[index].result, calls[index].name, result); err != nil {
+			return fmt.Errorf("%s: %v", calls[index].name, err)
+		}
+	}
+
+	return nil
+}
+
+// sendRawTransaction broadcasts the signed transaction. The node already knowing it, e.g. from an endpoint which accepted it
+// before the client failed over, counts as sent. isAmbiguous tells that the transaction may have reached the network
+// although an error is returned, so that its nonce must not be handed out again.
+func (controller InfuraController) sendRawTransaction(rawTransaction []byte) (bool, error) {
+	if controller.client == nil {
+		return false, errors.New("RPC endpoints are not set")
+	}
+
+	response := new(infuraResponse)
+
+	failures, err := controller.client.postFailingOver(makeRequestPayload(
+		"eth_sendRawTransaction",
+		[]interface{}{hexutil.Bytes(rawTransaction).String()},
+	), response)
+
+	if err != nil {
+		return true, err
+	}
+
+	if response.Error == nil || isKnownTransactionError(response.Error.Message) {
+		return false, nil
+	}
+
+	// The rejection may be caused by the transaction itself, sent through an endpoint which failed.
+	return failures > 0, errors.New(response.Error.Message)
+}
+
+// isKnownTransactionError tells whether the node rejected the transaction because it already has it.
+func isKnownTransactionError(message string) bool {
+	message = strings.ToLower(message)
+
+	return strings.Contains(message, "already known") || strings.Contains(message, "known transaction")
+}
+
+// getPendingNonce returns the next nonce of the account, counting its transactions in the pool.
+func (controller InfuraController) getPendingNonce(account common.Address) (uint64, error) {
+	response, err := controller.callInfura(makeRequestPayload(
+		"eth_getTransactionCount",
+		[]interface{}{account, "pending"},
+	))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return hexutil.DecodeUint64(response.Result)
+}
+
+func (controller InfuraController) callContract(name string, abi abi.ABI, targetContract common.Address, arguments ...interface{}) ([]byte, error) {
+	packedData, err := abi.Pack(name, arguments...)
 
 	if err != nil {
 		return nil, err
 	}
-	payload := makeCallRequestPayload(targetContract, packedData)
 
-	response, err := controller.callInfura(payload)
+	response, err := controller.callInfura(makeCallRequestPayload(targetContract, packedData))
 
 	if err != nil {
 		return nil, err
 	}
 
-	resultAddress := common.HexToAddress(response.Result)
+	return common.FromHex(response.Result), nil
+}
+
+// retrieveParameter unpacks the result of a constant call into the declared type, e.g. *bool, **big.Int or *common.Address.
+func (controller InfuraController) retrieveParameter(result interface{}, name string, abi abi.ABI, targetContract common.Address, arguments ...interface{}) error {
+	data, err := controller.callContract(name, abi, targetContract, arguments...)
+
+	if err != nil {
+		return err
+	}
+
+	if err := abi.Unpack(result, name, data); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
 
-	return &resultAddress, nil
+	return nil
 }
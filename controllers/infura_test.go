@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"strings"
 	"testing"
@@ -42,20 +43,17 @@ func TestMakeEstimateGasRequestPayload(t *testing.T) {
 
 func TestInfuraController(t *testing.T) {
 
-	controller := MakeInfuraController("test", false)
+	assert.Equal(t, RPCEndpoint{URL: mainnetInfuraEndpoint + "test"}, MakeInfuraEndpoint("test", false))
+	assert.Equal(t, RPCEndpoint{URL: testnetInfuraEndpoint + "test"}, MakeInfuraEndpoint("test", true))
 
-	assert.Equal(t, mainnetInfuraEndpoint+"test", controller.infuraEndpoint)
-
-	controller = MakeInfuraController("test", true)
-
-	assert.Equal(t, testnetInfuraEndpoint+"test", controller.infuraEndpoint)
+	controller := testInfuraController()
 
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 
 	httpmock.RegisterResponder(
 		http.MethodPost,
-		controller.infuraEndpoint,
+		testRPCEndpoint,
 		func(request *http.Request) (*http.Response, error) {
 			defer request.Body.Close()
 
@@ -104,28 +102,22 @@ func TestInfuraController(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	param, err := controller.retrieveParameter("totalSupply", tokenABI, tokenContractAddress)
-
-	if assert.NoError(t, err) {
-		assert.Equal(t, common.HexToAddress("0xabc"), *param)
-	}
+	var totalSupply *big.Int
 
-	param, err = controller.retrieveParameter("totalSupply", abi.ABI{}, tokenContractAddress)
+	// Results which aren't a whole uint256 word can't be decoded.
+	err = controller.retrieveParameter(&totalSupply, "totalSupply", tokenABI, tokenContractAddress)
 
 	if assert.Error(t, err) {
-		assert.Nil(t, param)
+		assert.Nil(t, totalSupply)
 	}
 
-	param, err = controller.retrieveParameter("totalSupplies", tokenABI, tokenContractAddress)
-
-	if assert.Error(t, err) {
-		assert.Nil(t, param)
-	}
+	assert.Error(t, controller.retrieveParameter(&totalSupply, "totalSupply", abi.ABI{}, tokenContractAddress))
+	assert.Error(t, controller.retrieveParameter(&totalSupply, "totalSupplies", tokenABI, tokenContractAddress))
 
 	// TEST FOR UNLIKELY ERRORS
 
 	httpmock.RegisterResponder(http.MethodPost,
-		controller.infuraEndpoint,
+		testRPCEndpoint,
 		func(request *http.Request) (*http.Response, error) {
 			return nil, errors.New("test_error")
 		},
@@ -137,14 +129,10 @@ func TestInfuraController(t *testing.T) {
 		assert.Nil(t, response)
 	}
 
-	param, err = controller.retrieveParameter("totalSupply", tokenABI, tokenContractAddress)
-
-	if assert.Error(t, err) {
-		assert.Nil(t, param)
-	}
+	assert.Error(t, controller.retrieveParameter(&totalSupply, "totalSupply", tokenABI, tokenContractAddress))
 
 	httpmock.RegisterResponder(http.MethodPost,
-		controller.infuraEndpoint,
+		testRPCEndpoint,
 		func(request *http.Request) (*http.Response, error) {
 			return httpmock.NewStringResponse(http.StatusOK, "{C}"), nil
 		},
@@ -156,14 +144,10 @@ func TestInfuraController(t *testing.T) {
 		assert.Nil(t, response)
 	}
 
-	param, err = controller.retrieveParameter("totalSupply", tokenABI, tokenContractAddress)
-
-	if assert.Error(t, err) {
-		assert.Nil(t, param)
-	}
+	assert.Error(t, controller.retrieveParameter(&totalSupply, "totalSupply", tokenABI, tokenContractAddress))
 
 	httpmock.RegisterResponder(http.MethodPost,
-		controller.infuraEndpoint,
+		testRPCEndpoint,
 		func(request *http.Request) (*http.Response, error) {
 			return httpmock.NewStringResponse(
 				http.StatusOK,
@@ -178,10 +162,104 @@ func TestInfuraController(t *testing.T) {
 		assert.Nil(t, response)
 	}
 
-	param, err = controller.retrieveParameter("totalSupply", tokenABI, tokenContractAddress)
+	assert.Error(t, controller.retrieveParameter(&totalSupply, "totalSupply", tokenABI, tokenContractAddress))
 
-	if assert.Error(t, err) {
-		assert.Nil(t, param)
+}
+
+func TestInfuraController_CallInfuraBatch(t *testing.T) {
+	controller := testInfuraController()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Batch responses may come in any order.
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		httpmock.NewStringResponder(http.StatusOK, `[
+			{"jsonrpc": "2.0", "id": 2, "error": {"code": -32000, "message": "execution reverted"}},
+			{"jsonrpc": "2.0", "id": 1, "result": "0x1"}
+		]`),
+	)
+
+	responses, err := controller.callInfuraBatch([]requestPayload{
+		makeRequestPayload("eth_blockNumber", []interface{}{}),
+		makeRequestPayload("eth_call", []interface{}{}),
+	})
+
+	if assert.NoError(t, err) && assert.Len(t, responses, 2) {
+		assert.Equal(t, `"0x1"`, string(responses[0].Result))
+		assert.NoError(t, responses[0].err())
+		assert.EqualError(t, responses[1].err(), "execution reverted")
+	}
+
+	_, err = controller.callInfuraBatch([]requestPayload{
+		makeRequestPayload("eth_blockNumber", []interface{}{}),
+		makeRequestPayload("eth_call", []interface{}{}),
+		makeRequestPayload("eth_chainId", []interface{}{}),
+	})
+
+	assert.EqualError(t, err, "batch response lacks eth_chainId")
+
+	var isICO bool
+
+	err = controller.retrieveParameters(abi.ABI{}, common.HexToAddress("0x123"), []contractCall{makeContractCall(&isICO, "isIco")})
+
+	assert.EqualError(t, err, "method 'isIco' not found")
+}
+
+func TestInfuraController_RetrieveParameters(t *testing.T) {
+	controller := testInfuraController()
+
+	crowdsaleABI, err := abi.JSON(strings.NewReader(gocontracts.MocrowCoinCrowdsaleABI))
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
 	}
 
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	investment := big.NewInt(0).Lsh(big.NewInt(1), 255)
+	isICOResult := common.BigToHash(big.NewInt(1)).Hex()
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		batchResponder(func(request *http.Request) (*http.Response, error) {
+			requestBody := new(requestPayload)
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return nil, err
+			}
+
+			result := isICOResult
+
+			if data := requestBody.Params[0].(map[string]interface{})["data"].(string); len(data) > 10 {
+				result = common.BigToHash(investment).Hex()
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "`+result+`"}`), nil
+		}),
+	)
+
+	var isICO bool
+	var icoInvestment *big.Int
+
+	err = controller.retrieveParameters(crowdsaleABI, common.HexToAddress("0x456"), []contractCall{
+		makeContractCall(&isICO, "isIco"),
+		makeContractCall(&icoInvestment, "getIcoInvestment", common.HexToAddress("0x123")),
+	})
+
+	if assert.NoError(t, err) {
+		assert.True(t, isICO)
+		assert.Equal(t, investment, icoInvestment)
+	}
+
+	// Values out of the range of the declared type are errors rather than being cut.
+	isICOResult = common.BigToHash(big.NewInt(2)).Hex()
+
+	err = controller.retrieveParameters(crowdsaleABI, common.HexToAddress("0x456"), []contractCall{makeContractCall(&isICO, "isIco")})
+
+	assert.Error(t, err)
 }
@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"MCW-btc-module/helpers"
+)
+
+const (
+	LightningInvoiceStateOpen     = "OPEN"
+	LightningInvoiceStateSettled  = "SETTLED"
+	LightningInvoiceStateCanceled = "CANCELED"
+	LightningInvoiceStateAccepted = "ACCEPTED"
+)
+
+const (
+	// lndRequestTimeout bounds every request to LND.
+	lndRequestTimeout = 30 * time.Second
+	// maxLndRetryInterval caps the backoff of invoice polls failing in a row.
+	maxLndRetryInterval = 5 * time.Minute
+)
+
+type LightningController struct {
+	endpoint      string
+	macaroon      string
+	invoiceExpiry time.Duration
+	pollInterval  time.Duration
+	client        *http.Client
+}
+
+type (
+	LightningInvoice struct {
+		PaymentHash    string
+		PaymentRequest string
+		State          string
+		AmountPaid     int64
+		ExpiresAt      time.Time
+	}
+
+	lndAddInvoiceRequest struct {
+		Memo   string `json:"memo"`
+		Value  string `json:"value"`
+		Expiry string `json:"expiry"`
+	}
+
+	lndAddInvoiceResponse struct {
+		RHash          []byte `json:"r_hash"`
+		PaymentRequest string `json:"payment_request"`
+	}
+
+	lndInvoice struct {
+		RHash          []byte `json:"r_hash"`
+		PaymentRequest string `json:"payment_request"`
+		Settled        bool   `json:"settled"`
+		State          string `json:"state"`
+		AmountPaidSat  string `json:"amt_paid_sat"`
+		CreationDate   string `json:"creation_date"`
+		Expiry         string `json:"expiry"`
+	}
+
+	lndError struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+)
+
+// MakeLightningController creates a client for LND REST API. The TLS certificate is only needed when LND uses a self-signed one.
+func MakeLightningController(endpoint string, macaroonPath string, tlsCertificatePath string, invoiceExpiry time.Duration) (*LightningController, error) {
+	macaroon, err := ioutil.ReadFile(macaroonPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: lndRequestTimeout}
+
+	if tlsCertificatePath != "" {
+		certificate, err := ioutil.ReadFile(tlsCertificatePath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		certificates := x509.NewCertPool()
+
+		if !certificates.AppendCertsFromPEM(certificate) {
+			return nil, errors.New("invalid lnd tls certificate")
+		}
+
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: certificates}}
+	}
+
+	return &LightningController{
+		endpoint:      strings.TrimRight(endpoint, "/"),
+		macaroon:      hex.EncodeToString(macaroon),
+		invoiceExpiry: invoiceExpiry,
+		pollInterval:  10 * time.Second,
+		client:        client,
+	}, nil
+}
+
+func (controller LightningController) callLnd(method string, path string, body interface{}, result interface{}) error {
+	requestBody := []byte{}
+
+	if body != nil {
+		marshaledBody, err := json.Marshal(body)
+
+		if err != nil {
+			return err
+		}
+
+		requestBody = marshaledBody
+	}
+
+	status, responseBody, err := helpers.RequestWithClient(
+		controller.client,
+		method,
+		controller.endpoint+path,
+		helpers.Headers{"Content-Type": "application/json", "Grpc-Metadata-macaroon": controller.macaroon},
+		requestBody,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		response := new(lndError)
+
+		if err := json.Unmarshal(responseBody, response); err == nil && (response.Error != "" || response.Message != "") {
+			if response.Message != "" {
+				return errors.New(response.Message)
+			}
+			return errors.New(response.Error)
+		}
+
+		return fmt.Errorf("lnd responded with status %d", status)
+	}
+
+	return json.Unmarshal(responseBody, result)
+}
+
+func (controller LightningController) CreateInvoice(amountSatoshis int64, memo string) (*LightningInvoice, error) {
+	if amountSatoshis <= 0 {
+		return nil, errors.New("invalid invoice amount")
+	}
+
+	response := new(lndAddInvoiceResponse)
+
+	err := controller.callLnd(http.MethodPost, "/v1/invoices", lndAddInvoiceRequest{
+		Memo:   memo,
+		Value:  strconv.FormatInt(amountSatoshis, 10),
+		Expiry: strconv.FormatInt(int64(controller.invoiceExpiry.Seconds()), 10),
+	}, response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LightningInvoice{
+		PaymentHash:    hex.EncodeToString(response.RHash),
+		PaymentRequest: response.PaymentRequest,
+		State:          LightningInvoiceStateOpen,
+		ExpiresAt:      time.Now().Add(controller.invoiceExpiry),
+	}, nil
+}
+
+func (controller LightningController) GetInvoice(paymentHash string) (*LightningInvoice, error) {
+	response := new(lndInvoice)
+
+	if err := controller.callLnd(http.MethodGet, "/v1/invoice/"+paymentHash, nil, response); err != nil {
+		return nil, err
+	}
+
+	invoice := &LightningInvoice{
+		PaymentHash:    hex.EncodeToString(response.RHash),
+		PaymentRequest: response.PaymentRequest,
+		State:          response.State,
+	}
+
+	// Older LND versions do not report the state, only the 'settled' flag.
+	if invoice.State == "" {
+		invoice.State = LightningInvoiceStateOpen
+
+		if response.Settled {
+			invoice.State = LightningInvoiceStateSettled
+		}
+	}
+
+	if response.AmountPaidSat != "" {
+		amountPaid, err := strconv.ParseInt(response.AmountPaidSat, 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		invoice.AmountPaid = amountPaid
+	}
+
+	creationDate, _ := strconv.ParseInt(response.CreationDate, 10, 64)
+	expiry, _ := strconv.ParseInt(response.Expiry, 10, 64)
+
+	invoice.ExpiresAt = time.Unix(creationDate+expiry, 0)
+
+	return invoice, nil
+}
+
+// waitForSettlement polls LND until the invoice is settled and returns the amount paid in satoshis.
+// Failing polls are retried with backoff until the invoice expires.
+func (controller LightningController) waitForSettlement(paymentHash string) (int64, error) {
+	// Until LND reports the invoice, assume it expires a full expiry period from now.
+	expiresAt := time.Now().Add(controller.invoiceExpiry)
+	interval := controller.pollInterval
+
+	for {
+		<-time.After(interval)
+
+		invoice, err := controller.GetInvoice(paymentHash)
+
+		if err != nil {
+			log.Println("UNABLE TO CHECK INVOICE", paymentHash, err)
+
+			if time.Now().After(expiresAt) {
+				return 0, fmt.Errorf("invoice expired before its state could be checked: %s", err)
+			}
+
+			if interval *= 2; interval > maxLndRetryInterval {
+				interval = maxLndRetryInterval
+			}
+
+			continue
+		}
+
+		interval = controller.pollInterval
+		expiresAt = invoice.ExpiresAt
+
+		switch invoice.State {
+		case LightningInvoiceStateSettled:
+			return invoice.AmountPaid, nil
+		case LightningInvoiceStateCanceled:
+			return 0, errors.New("invoice canceled")
+		}
+
+		if invoice.State == LightningInvoiceStateOpen && time.Now().After(invoice.ExpiresAt) {
+			return 0, errors.New("invoice expired")
+		}
+	}
+}
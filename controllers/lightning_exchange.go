@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"log"
+	"math/big"
+
+	"MCW-btc-module/helpers"
+	"MCW-btc-module/model"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type LightningExchangeController struct {
+	*ExchangeController
+	*LightningController
+	invoiceMemo string
+}
+
+func MakeLightningExchangeController(
+	exchangeController *ExchangeController,
+	lightningController *LightningController,
+	invoiceMemo string,
+) *LightningExchangeController {
+	return &LightningExchangeController{
+		ExchangeController:  exchangeController,
+		LightningController: lightningController,
+		invoiceMemo:         invoiceMemo,
+	}
+}
+
+// CreateInvoiceEntry issues an invoice for the BTC amount, e.g. "0.015", and stores it as a new purchase.
+func (controller *LightningExchangeController) CreateInvoiceEntry(ethereumAddress string, amount string) (*model.BTCTransaction, error) {
+	satoshis, err := helpers.BTCToSatoshis(amount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	invoice, err := controller.LightningController.CreateInvoice(satoshis, controller.invoiceMemo)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &model.BTCTransaction{
+		EthereumAddress: ethereumAddress,
+		PaymentHash:     invoice.PaymentHash,
+		PaymentRequest:  invoice.PaymentRequest,
+		Status:          model.TRANSACTON_STATUS_NEW,
+	}
+
+	if err := controller.issueQuote(transaction); err != nil {
+		return nil, err
+	}
+
+	if err := controller.database.Create(transaction).Error; err != nil {
+		return nil, err
+	}
+
+	controller.recordRate(transaction, model.RATE_HISTORY_KIND_QUOTE, transaction.QuoteRate, transaction.QuoteTokenRate)
+
+	return transaction, nil
+}
+
+// BuyTokensWithInvoice waits for the invoice to be settled and mints tokens the same way BuyTokens does.
+func (controller *LightningExchangeController) BuyTokensWithInvoice(transaction *model.BTCTransaction) {
+	amountPaid, err := controller.LightningController.waitForSettlement(transaction.PaymentHash)
+
+	if err != nil {
+		transaction.Error = err.Error()
+		transaction.Status = model.TRANSACTION_STATUS_ERROR
+		controller.database.Save(transaction)
+		return
+	}
+
+	transaction.SatoshisTransferred = amountPaid
+	controller.database.Save(transaction)
+
+	rate, tokenRate, rateTime, err := controller.getPurchaseRates(transaction)
+
+	if err == ErrQuoteExpired {
+		controller.rejectExpiredQuote(transaction)
+		return
+	}
+
+	if err != nil {
+		transaction.Error = err.Error()
+		transaction.Status = model.TRANSACTION_STATUS_ERROR
+		controller.database.Save(transaction)
+		return
+	}
+
+	if err := controller.checkPurchaseRate(rate, rateTime); err != nil {
+		controller.holdForReview(transaction, err)
+		return
+	}
+
+	weiAmount := helpers.SatoshisToWei(transaction.SatoshisTransferred, rate)
+
+	acceptedWei, refundedWei, err := controller.TokenManagementController.CheckInvestmentLimits(
+		common.HexToAddress(transaction.EthereumAddress),
+		weiAmount,
+	)
+
+	// A settled invoice cannot be topped up, so payments below the minimum are refunded.
+	if err == ErrBelowMinimalInvestment {
+		acceptedWei, refundedWei, err = big.NewInt(0), weiAmount, nil
+	}
+
+	if err != nil {
+		transaction.Error = err.Error()
+		transaction.Status = model.TRANSACTION_STATUS_ERROR
+		controller.database.Save(transaction)
+		return
+	}
+
+	controller.completePurchase(transaction, rate, tokenRate, acceptedWei, refundedWei)
+}
+
+func (controller *LightningExchangeController) ResumeMonitoring() {
+	unfinishedTransactions := new([]model.BTCTransaction)
+
+	if err := controller.database.Where(
+		"status = ? and payment_hash <> ''",
+		model.TRANSACTON_STATUS_NEW,
+	).Find(unfinishedTransactions).Error; err != nil {
+		log.Println(err)
+	}
+
+	for index := range *unfinishedTransactions {
+		go controller.BuyTokensWithInvoice(&(*unfinishedTransactions)[index])
+	}
+}
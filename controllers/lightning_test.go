@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"MCW-btc-module/model"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func makeFakeLnd(states []string) *httptest.Server {
+	paymentHash := []byte{0xab, 0xcd}
+	polls := 0
+
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Grpc-Metadata-macaroon") != hex.EncodeToString([]byte("macaroon")) {
+			writer.WriteHeader(http.StatusUnauthorized)
+			writer.Write([]byte(`{"error": "permission denied", "code": 2}`))
+			return
+		}
+
+		switch {
+		case request.Method == http.MethodPost && request.URL.Path == "/v1/invoices":
+			body := new(lndAddInvoiceRequest)
+
+			if err := json.NewDecoder(request.Body).Decode(body); err != nil || body.Value != "1500000" {
+				writer.WriteHeader(http.StatusBadRequest)
+				writer.Write([]byte(`{"error": "invalid request", "code": 3}`))
+				return
+			}
+
+			json.NewEncoder(writer).Encode(lndAddInvoiceResponse{RHash: paymentHash, PaymentRequest: "lntb15m1test"})
+		case request.Method == http.MethodGet && request.URL.Path == "/v1/invoice/"+hex.EncodeToString(paymentHash):
+			state := states[polls]
+
+			if polls < len(states)-1 {
+				polls++
+			}
+
+			amountPaid := "0"
+
+			if state == LightningInvoiceStateSettled {
+				amountPaid = "1500000"
+			}
+
+			json.NewEncoder(writer).Encode(lndInvoice{
+				RHash:          paymentHash,
+				PaymentRequest: "lntb15m1test",
+				State:          state,
+				AmountPaidSat:  amountPaid,
+				CreationDate:   strconv.FormatInt(time.Now().Unix(), 10),
+				Expiry:         "3600",
+			})
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte(`{"error": "not found", "code": 5}`))
+		}
+	}))
+}
+
+func makeTestLightningController(t *testing.T, endpoint string) *LightningController {
+	macaroonFile, err := ioutil.TempFile("", "macaroon")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	defer os.Remove(macaroonFile.Name())
+
+	macaroonFile.Write([]byte("macaroon"))
+	macaroonFile.Close()
+
+	controller, err := MakeLightningController(endpoint+"/", macaroonFile.Name(), "", time.Hour)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	controller.pollInterval = time.Millisecond
+
+	return controller
+}
+
+func TestMakeLightningController(t *testing.T) {
+	controller := makeTestLightningController(t, "https://localhost:8080")
+
+	assert.Equal(t, "https://localhost:8080", controller.endpoint)
+	assert.Equal(t, hex.EncodeToString([]byte("macaroon")), controller.macaroon)
+	assert.Equal(t, lndRequestTimeout, controller.client.Timeout)
+
+	_, err := MakeLightningController("https://localhost:8080", "missing.macaroon", "", time.Hour)
+
+	assert.Error(t, err)
+}
+
+func TestLightningController_CreateInvoice(t *testing.T) {
+	server := makeFakeLnd([]string{LightningInvoiceStateOpen})
+	defer server.Close()
+
+	controller := makeTestLightningController(t, server.URL)
+
+	invoice, err := controller.CreateInvoice(1500000, "memo")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "abcd", invoice.PaymentHash)
+		assert.Equal(t, "lntb15m1test", invoice.PaymentRequest)
+		assert.Equal(t, LightningInvoiceStateOpen, invoice.State)
+	}
+
+	_, err = controller.CreateInvoice(1000, "memo")
+
+	assert.EqualError(t, err, "invalid request")
+
+	_, err = controller.CreateInvoice(0, "memo")
+
+	assert.Error(t, err)
+
+	controller.macaroon = ""
+
+	_, err = controller.CreateInvoice(1500000, "memo")
+
+	assert.EqualError(t, err, "permission denied")
+}
+
+func TestLightningController_waitForSettlement(t *testing.T) {
+	server := makeFakeLnd([]string{LightningInvoiceStateOpen, LightningInvoiceStateSettled})
+	defer server.Close()
+
+	controller := makeTestLightningController(t, server.URL)
+
+	amountPaid, err := controller.waitForSettlement("abcd")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1500000), amountPaid)
+	}
+}
+
+func TestLightningController_waitForSettlement_Canceled(t *testing.T) {
+	server := makeFakeLnd([]string{LightningInvoiceStateOpen, LightningInvoiceStateCanceled})
+	defer server.Close()
+
+	controller := makeTestLightningController(t, server.URL)
+
+	_, err := controller.waitForSettlement("abcd")
+
+	assert.EqualError(t, err, "invoice canceled")
+
+	_, err = controller.GetInvoice("ef01")
+
+	assert.EqualError(t, err, "not found")
+}
+
+func TestLightningController_waitForSettlement_Unreachable(t *testing.T) {
+	polls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		polls++
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		writer.Write([]byte(`{"error": "unavailable", "code": 14}`))
+	}))
+
+	defer server.Close()
+
+	controller := makeTestLightningController(t, server.URL)
+	controller.invoiceExpiry = 50 * time.Millisecond
+
+	_, err := controller.waitForSettlement("abcd")
+
+	assert.EqualError(t, err, "invoice expired before its state could be checked: unavailable")
+
+	// The polls back off instead of hammering LND every millisecond.
+	assert.True(t, polls < 10, polls)
+}
+
+func TestLightningExchangeController_ResumeMonitoring(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.DropTableIfExists(model.BTCTransaction{})
+	db.AutoMigrate(model.BTCTransaction{})
+
+	server := makeFakeLnd([]string{LightningInvoiceStateOpen, LightningInvoiceStateCanceled})
+	defer server.Close()
+
+	pending := &model.BTCTransaction{PaymentHash: "abcd", Status: model.TRANSACTON_STATUS_NEW}
+	onChain := &model.BTCTransaction{BitcoinAddress: "mv4rnyY3Su5gjcDNzbMLKBQkBicCtHUtFB", Status: model.TRANSACTON_STATUS_NEW}
+
+	db.Create(pending)
+	db.Create(onChain)
+
+	controller := MakeLightningExchangeController(&ExchangeController{database: db}, makeTestLightningController(t, server.URL), "")
+
+	controller.ResumeMonitoring()
+
+	stored := model.BTCTransaction{}
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if db.First(&stored, pending.ID); stored.Status != model.TRANSACTON_STATUS_NEW {
+			break
+		}
+	}
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_ERROR), stored.Status)
+	assert.Equal(t, "invoice canceled", stored.Error)
+
+	// On-chain purchases are resumed by the exchange controller.
+	storedOnChain := model.BTCTransaction{}
+
+	if assert.NoError(t, db.First(&storedOnChain, onChain.ID).Error) {
+		assert.Equal(t, int8(model.TRANSACTON_STATUS_NEW), storedOnChain.Status)
+	}
+}
+
+func TestLightningExchangeController_BuyTokensWithInvoice_TooFewRates(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	defer db.Close()
+
+	db.DB().SetMaxOpenConns(1)
+
+	if !assert.NoError(t, model.Migrate(db)) {
+		t.FailNow()
+	}
+
+	server := makeFakeLnd([]string{LightningInvoiceStateSettled})
+	defer server.Close()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// The fake LND is a real server.
+	httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip)
+
+	infuraController := testInfuraController()
+
+	// Every crowdsale call answers 1, so the crowdsale is in pre-ICO.
+	registerFakeNode(infuraController, map[string]string{
+		"eth_call": `"0x0000000000000000000000000000000000000000000000000000000000000001"`,
+	})
+
+	tokenManagementController, err := MakeTokenManagementController(infuraController, "", "")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// One source is 10x off, which leaves two of the three required sources.
+	rateOracleController, err := MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 10, nil},
+		mockRateSource{"b", 100, nil},
+		mockRateSource{"c", 10.1, nil},
+	}, 0.05, 3)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	quoteController, err := MakeQuoteController("secret", time.Hour, QuotePolicyRequote)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	rateCircuitBreakerController, err := MakeRateCircuitBreakerController(0, 0.05, 0)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	controller := MakeLightningExchangeController(&ExchangeController{
+		TokenManagementController:    *tokenManagementController,
+		RateOracleController:         rateOracleController,
+		QuoteController:              quoteController,
+		RateCircuitBreakerController: rateCircuitBreakerController,
+		database:                     db,
+	}, makeTestLightningController(t, server.URL), "")
+
+	phase, err := tokenManagementController.GetPhase()
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	tokenRate, err := tokenManagementController.GetTokenRate()
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// The invoice was quoted while the sources agreed.
+	transaction := &model.BTCTransaction{PaymentHash: "abcd", Status: model.TRANSACTON_STATUS_NEW}
+	quoteController.IssueQuote(transaction, 10, tokenRate, phase)
+	db.Create(transaction)
+
+	controller.BuyTokensWithInvoice(transaction)
+
+	stored := model.BTCTransaction{}
+
+	if assert.NoError(t, db.First(&stored, transaction.ID).Error) {
+		assert.Equal(t, int8(model.TRANSACTION_STATUS_NEEDS_REVIEW), stored.Status)
+		assert.Equal(t, "only 2 of 3 required rates are within allowed deviation", stored.Error)
+		assert.Equal(t, int64(1500000), stored.SatoshisTransferred)
+		assert.Equal(t, "", stored.MintTransactionHash)
+	}
+}
@@ -9,17 +9,24 @@ import (
 type MonitoringController struct {
 	BlockcypherController
 	BlocktrailController
+	pollInterval time.Duration
 }
 
 func MakeMonitoringController(blockcypherController BlockcypherController, blocktrailController BlocktrailController) MonitoringController {
 	return MonitoringController{
 		BlockcypherController: blockcypherController,
 		BlocktrailController:  blocktrailController,
+		pollInterval:          3 * time.Minute,
 	}
 }
 
-func (controller MonitoringController) waitForTransfer(address string) (float64, error) {
-	ticker := time.NewTicker(3 * time.Minute)
+func (controller MonitoringController) waitForTransfer(address string) (int64, error) {
+	return controller.waitForTransferAbove(address, 0)
+}
+
+// waitForTransferAbove returns the confirmed balance in satoshis once it exceeds previousBalance.
+func (controller MonitoringController) waitForTransferAbove(address string, previousBalance int64) (int64, error) {
+	ticker := time.NewTicker(controller.pollInterval)
 	startingDate := time.Now()
 	for {
 		select {
@@ -34,9 +41,9 @@ func (controller MonitoringController) waitForTransfer(address string) (float64,
 				fmt.Println(err)
 			}
 
-			if confirmedBalance > 0 {
+			if balance := int64(confirmedBalance); balance > previousBalance {
 				ticker.Stop()
-				return float64(confirmedBalance) / 100000000, nil
+				return balance, nil
 			}
 
 			if time.Now().Unix()-startingDate.Unix() == int64(time.Hour.Seconds())*24 {
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/jarcoal/httpmock.v1"
@@ -16,6 +17,7 @@ func TestMakeMonitoringController(t *testing.T) {
 	controller := MakeMonitoringController(blockcypherController, blocktrailController)
 
 	assert.Equal(t, controller.endpoint, blocktrailController.endpoint)
+	assert.Equal(t, 3*time.Minute, controller.pollInterval)
 }
 
 func TestMonitoringController_waitForTransfer(t *testing.T) {
@@ -23,6 +25,7 @@ func TestMonitoringController_waitForTransfer(t *testing.T) {
 	blocktrailController := MakeBlocktrailController("key", true)
 
 	controller := MakeMonitoringController(blockcypherController, blocktrailController)
+	controller.pollInterval = time.Millisecond
 
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -58,6 +61,6 @@ func TestMonitoringController_waitForTransfer(t *testing.T) {
 	value, err := controller.waitForTransfer(testAddress)
 
 	if assert.NoError(t, err) {
-		assert.Equal(t, 0.00015, value)
+		assert.Equal(t, int64(15000), value)
 	}
 }
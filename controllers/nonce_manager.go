@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"MCW-btc-module/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+)
+
+type accountNonces struct {
+	isSynced  bool
+	nextNonce uint64
+	// Nonces handed out, but neither sent nor released yet.
+	inFlight map[uint64]bool
+	// Nonces which must be handed out again, lowest first.
+	released []uint64
+}
+
+// NonceManager hands out the nonces of the signing accounts, so concurrent transactions never share one.
+// The next nonces are stored in the database if it's set.
+type NonceManager struct {
+	InfuraController
+	database *gorm.DB
+	mutex    *sync.Mutex
+	accounts map[common.Address]*accountNonces
+}
+
+func MakeNonceManager(infuraController InfuraController, database *gorm.DB) *NonceManager {
+	return &NonceManager{
+		InfuraController: infuraController,
+		database:         database,
+		mutex:            &sync.Mutex{},
+		accounts:         make(map[common.Address]*accountNonces),
+	}
+}
+
+func (manager *NonceManager) getStoredNonce(account common.Address) (uint64, error) {
+	if manager.database == nil {
+		return 0, nil
+	}
+
+	accountNonce := new(model.AccountNonce)
+
+	err := manager.database.Where("address = ?", strings.ToLower(account.Hex())).First(accountNonce).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+
+	return accountNonce.NextNonce, err
+}
+
+func (manager *NonceManager) storeNonce(account common.Address, nextNonce uint64) error {
+	if manager.database == nil {
+		return nil
+	}
+
+	return manager.database.
+		Where(model.AccountNonce{Address: strings.ToLower(account.Hex())}).
+		Assign(map[string]interface{}{"next_nonce": nextNonce}).
+		FirstOrCreate(new(model.AccountNonce)).Error
+}
+
+func (manager *NonceManager) getAccount(account common.Address) *accountNonces {
+	nonces, ok := manager.accounts[account]
+
+	if !ok {
+		nonces = &accountNonces{inFlight: make(map[uint64]bool)}
+		manager.accounts[account] = nonces
+	}
+
+	return nonces
+}
+
+// sync aligns the account with the node. Nonces below the highest handed out one which the node has never seen
+// belong to dropped transactions and are handed out again.
+func (manager *NonceManager) sync(account common.Address, nonces *accountNonces) error {
+	pendingNonce, err := manager.getPendingNonce(account)
+
+	if err != nil {
+		return err
+	}
+
+	nextNonce := pendingNonce
+
+	if !nonces.isSynced {
+		storedNonce, err := manager.getStoredNonce(account)
+
+		if err != nil {
+			return err
+		}
+
+		if storedNonce > nextNonce {
+			nextNonce = storedNonce
+		}
+	}
+
+	if nonces.nextNonce > nextNonce {
+		nextNonce = nonces.nextNonce
+	}
+
+	released := make([]uint64, 0)
+
+	for nonce := pendingNonce; nonce < nextNonce; nonce++ {
+		if !nonces.inFlight[nonce] {
+			released = append(released, nonce)
+		}
+	}
+
+	nonces.isSynced = true
+	nonces.nextNonce = nextNonce
+	nonces.released = released
+
+	return nil
+}
+
+// NextNonce reserves the lowest free nonce of the account. The nonce must be either marked sent or released.
+func (manager *NonceManager) NextNonce(account common.Address) (uint64, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	nonces := manager.getAccount(account)
+
+	if !nonces.isSynced {
+		if err := manager.sync(account, nonces); err != nil {
+			return 0, err
+		}
+	}
+
+	var nonce uint64
+
+	if len(nonces.released) > 0 {
+		nonce = nonces.released[0]
+		nonces.released = nonces.released[1:]
+	} else {
+		nonce = nonces.nextNonce
+		nonces.nextNonce++
+
+		if err := manager.storeNonce(account, nonces.nextNonce); err != nil {
+			nonces.nextNonce--
+
+			return 0, err
+		}
+	}
+
+	nonces.inFlight[nonce] = true
+
+	return nonce, nil
+}
+
+// MarkSent is called once the node has accepted the transaction with the nonce.
+func (manager *NonceManager) MarkSent(account common.Address, nonce uint64) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	delete(manager.getAccount(account).inFlight, nonce)
+}
+
+// Release returns the nonce of the transaction which didn't reach the node, so the next transaction fills the gap.
+func (manager *NonceManager) Release(account common.Address, nonce uint64) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	nonces := manager.getAccount(account)
+
+	if !nonces.inFlight[nonce] {
+		return
+	}
+
+	delete(nonces.inFlight, nonce)
+
+	nonces.released = append(nonces.released, nonce)
+
+	sort.Slice(nonces.released, func(i, j int) bool { return nonces.released[i] < nonces.released[j] })
+}
+
+// Resync makes the next reservation align the account with the node first.
+func (manager *NonceManager) Resync(account common.Address) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.getAccount(account).isSynced = false
+}
+
+// isNonceError tells whether the node rejected the transaction because the nonce is out of sync.
+func isNonceError(err error) bool {
+	message := strings.ToLower(err.Error())
+
+	return strings.Contains(message, "nonce too low") ||
+		strings.Contains(message, "nonce too high") ||
+		strings.Contains(message, "replacement transaction underpriced")
+}
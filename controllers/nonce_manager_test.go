@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"MCW-btc-module/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func TestNonceManager_NextNonce(t *testing.T) {
+	infuraController := testInfuraController()
+	account := common.HexToAddress("0x5aeda56215b167893e80b4fe645ba6d5bab767de")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{"eth_getTransactionCount": `"0x5"`})
+
+	manager := MakeNonceManager(infuraController, nil)
+
+	nonces := make(chan uint64, 20)
+	waitGroup := sync.WaitGroup{}
+
+	for i := 0; i < 20; i++ {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			nonce, err := manager.NextNonce(account)
+
+			if assert.NoError(t, err) {
+				nonces <- nonce
+			}
+		}()
+	}
+
+	waitGroup.Wait()
+	close(nonces)
+
+	handedOut := make(map[uint64]bool)
+
+	for nonce := range nonces {
+		handedOut[nonce] = true
+	}
+
+	assert.Len(t, handedOut, 20)
+
+	for nonce := uint64(5); nonce < 25; nonce++ {
+		assert.True(t, handedOut[nonce])
+	}
+}
+
+func TestNonceManager_Release(t *testing.T) {
+	infuraController := testInfuraController()
+	account := common.HexToAddress("0x5aeda56215b167893e80b4fe645ba6d5bab767de")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{"eth_getTransactionCount": `"0x5"`})
+
+	manager := MakeNonceManager(infuraController, nil)
+
+	for nonce := uint64(5); nonce < 8; nonce++ {
+		handedOut, err := manager.NextNonce(account)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, nonce, handedOut)
+		}
+	}
+
+	manager.MarkSent(account, 5)
+	manager.Release(account, 7)
+	manager.Release(account, 6)
+
+	// Released twice, handed out once.
+	manager.Release(account, 6)
+	manager.Release(account, 5)
+
+	for _, nonce := range []uint64{6, 7, 8} {
+		handedOut, err := manager.NextNonce(account)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, nonce, handedOut)
+		}
+	}
+}
+
+func TestNonceManager_Resync(t *testing.T) {
+	infuraController := testInfuraController()
+	account := common.HexToAddress("0x5aeda56215b167893e80b4fe645ba6d5bab767de")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{"eth_getTransactionCount": `"0x5"`})
+
+	manager := MakeNonceManager(infuraController, nil)
+
+	for i := 0; i < 4; i++ {
+		nonce, _ := manager.NextNonce(account)
+
+		if nonce != 7 {
+			manager.MarkSent(account, nonce)
+		}
+	}
+
+	// The transaction with nonce 6 has been dropped, 7 is still being sent.
+	registerFakeNode(infuraController, map[string]string{"eth_getTransactionCount": `"0x6"`})
+
+	manager.Resync(account)
+
+	for _, nonce := range []uint64{6, 8, 9} {
+		handedOut, err := manager.NextNonce(account)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, nonce, handedOut)
+		}
+	}
+
+	// The node is ahead after transactions sent elsewhere.
+	registerFakeNode(infuraController, map[string]string{"eth_getTransactionCount": `"0xc"`})
+
+	manager.Resync(account)
+
+	nonce, err := manager.NextNonce(account)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint64(12), nonce)
+	}
+}
+
+func TestNonceManager_Database(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.DropTableIfExists(model.AccountNonce{})
+	db.AutoMigrate(model.AccountNonce{})
+
+	infuraController := testInfuraController()
+	account := common.HexToAddress("0x5aeda56215b167893e80b4fe645ba6d5bab767de")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{"eth_getTransactionCount": `"0x5"`})
+
+	manager := MakeNonceManager(infuraController, db)
+
+	for i := 0; i < 3; i++ {
+		nonce, _ := manager.NextNonce(account)
+		manager.MarkSent(account, nonce)
+	}
+
+	accountNonce := model.AccountNonce{}
+
+	if assert.NoError(t, db.First(&accountNonce).Error) {
+		assert.Equal(t, "0x5aeda56215b167893e80b4fe645ba6d5bab767de", accountNonce.Address)
+		assert.Equal(t, uint64(8), accountNonce.NextNonce)
+	}
+
+	// After the restart the node only knows the transaction with nonce 5, so 6 and 7 are sent again.
+	registerFakeNode(infuraController, map[string]string{"eth_getTransactionCount": `"0x6"`})
+
+	manager = MakeNonceManager(infuraController, db)
+
+	for _, nonce := range []uint64{6, 7, 8} {
+		handedOut, err := manager.NextNonce(account)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, nonce, handedOut)
+		}
+	}
+
+	if assert.NoError(t, db.First(&accountNonce).Error) {
+		assert.Equal(t, uint64(9), accountNonce.NextNonce)
+	}
+}
+
+func TestNonceManager_NextNonce_Fail(t *testing.T) {
+	infuraController := testInfuraController()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{})
+
+	manager := MakeNonceManager(infuraController, nil)
+
+	_, err := manager.NextNonce(common.Address{})
+
+	assert.Error(t, err)
+}
+
+func TestIsNonceError(t *testing.T) {
+	assert.True(t, isNonceError(errors.New("nonce too low")))
+	assert.True(t, isNonceError(errors.New("replacement transaction underpriced")))
+	assert.False(t, isNonceError(errors.New("already known")))
+	assert.False(t, isNonceError(errors.New("insufficient funds for gas * price + value")))
+}
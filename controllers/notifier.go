@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"MCW-btc-module/helpers"
+)
+
+// Notifier alerts the operator.
+type Notifier interface {
+	Notify(message string) error
+}
+
+const (
+	NotifierLog     = "log"
+	NotifierWebhook = "webhook"
+)
+
+// MakeNotifier creates the notifier by its name. Webhooks get the message posted to the endpoint.
+func MakeNotifier(name string, endpoint string) (Notifier, error) {
+	switch name {
+	case NotifierLog:
+		return LogNotifier{}, nil
+	case NotifierWebhook:
+		if endpoint == "" {
+			return nil, errors.New("webhook endpoint must be set")
+		}
+
+		return WebhookNotifier{endpoint}, nil
+	}
+
+	return nil, fmt.Errorf("unknown notifier '%s'", name)
+}
+
+type LogNotifier struct{}
+
+func (notifier LogNotifier) Notify(message string) error {
+	log.Println("ALERT", message)
+
+	return nil
+}
+
+// WebhookNotifier posts {"text": message}, which Slack, Mattermost and Rocket.Chat incoming webhooks accept.
+type WebhookNotifier struct {
+	endpoint string
+}
+
+func (notifier WebhookNotifier) Notify(message string) error {
+	body, err := json.Marshal(map[string]string{"text": message})
+
+	if err != nil {
+		return err
+	}
+
+	status, _, err := helpers.Post(notifier.endpoint, helpers.Headers{"Content-Type": "application/json"}, body)
+
+	if err != nil {
+		return err
+	}
+
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with status %d", status)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func TestMakeNotifier(t *testing.T) {
+	notifier, err := MakeNotifier(NotifierLog, "")
+
+	if assert.NoError(t, err) {
+		assert.NoError(t, notifier.Notify("message"))
+	}
+
+	_, err = MakeNotifier(NotifierWebhook, "")
+
+	assert.EqualError(t, err, "webhook endpoint must be set")
+
+	_, err = MakeNotifier("pager", "")
+
+	assert.EqualError(t, err, "unknown notifier 'pager'")
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	notifier, _ := MakeNotifier(NotifierWebhook, "https://hooks.test/alerts")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	messages := make([]string, 0)
+	status := http.StatusOK
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		"https://hooks.test/alerts",
+		func(request *http.Request) (*http.Response, error) {
+			requestBody := make(map[string]string)
+
+			if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+				return nil, err
+			}
+
+			messages = append(messages, requestBody["text"])
+
+			return httpmock.NewStringResponse(status, "ok"), nil
+		},
+	)
+
+	if assert.NoError(t, notifier.Notify("low balance")) {
+		assert.Equal(t, []string{"low balance"}, messages)
+	}
+
+	status = http.StatusNotFound
+
+	assert.EqualError(t, notifier.Notify("low balance"), "webhook responded with status 404")
+}
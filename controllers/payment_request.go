@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"errors"
+
+	"MCW-btc-module/helpers"
+	"MCW-btc-module/model"
+)
+
+const (
+	QRCodeFormatPNG = "png"
+	QRCodeFormatSVG = "svg"
+)
+
+type PaymentRequestController struct {
+	label      string
+	qrCodeSize int
+}
+
+func MakePaymentRequestController(label string, qrCodeSize int) PaymentRequestController {
+	if qrCodeSize <= 0 {
+		qrCodeSize = 256
+	}
+
+	return PaymentRequestController{
+		label:      label,
+		qrCodeSize: qrCodeSize,
+	}
+}
+
+func (controller PaymentRequestController) GetPaymentURI(transaction *model.BTCTransaction, amount string) (string, error) {
+	return helpers.MakePaymentURI(transaction.BitcoinAddress, amount, controller.label)
+}
+
+// GetPaymentQRCode returns the encoded QR code of the payment URI along with its content type.
+func (controller PaymentRequestController) GetPaymentQRCode(transaction *model.BTCTransaction, amount string, format string) ([]byte, string, error) {
+	uri, err := controller.GetPaymentURI(transaction, amount)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case QRCodeFormatPNG, "":
+		image, err := helpers.EncodeQRCodePNG(uri, controller.qrCodeSize)
+		return image, "image/png", err
+	case QRCodeFormatSVG:
+		image, err := helpers.EncodeQRCodeSVG(uri, controller.qrCodeSize)
+		return image, "image/svg+xml", err
+	}
+
+	return nil, "", errors.New("unsupported qr code format")
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"MCW-btc-module/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakePaymentRequestController(t *testing.T) {
+	controller := MakePaymentRequestController("label", 0)
+
+	assert.Equal(t, "label", controller.label)
+	assert.Equal(t, 256, controller.qrCodeSize)
+
+	controller = MakePaymentRequestController("label", 512)
+
+	assert.Equal(t, 512, controller.qrCodeSize)
+}
+
+func TestPaymentRequestController_GetPaymentURI(t *testing.T) {
+	controller := MakePaymentRequestController("MCW", 0)
+	transaction := &model.BTCTransaction{BitcoinAddress: "mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt"}
+
+	uri, err := controller.GetPaymentURI(transaction, "0.5")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "bitcoin:mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt?amount=0.5&label=MCW", uri)
+	}
+
+	_, err = controller.GetPaymentURI(transaction, "half")
+
+	assert.Error(t, err)
+}
+
+func TestPaymentRequestController_GetPaymentQRCode(t *testing.T) {
+	controller := MakePaymentRequestController("MCW", 0)
+	transaction := &model.BTCTransaction{BitcoinAddress: "mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt"}
+
+	image, contentType, err := controller.GetPaymentQRCode(transaction, "", "")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "image/png", contentType)
+		assert.NotEmpty(t, image)
+	}
+
+	image, contentType, err = controller.GetPaymentQRCode(transaction, "", QRCodeFormatSVG)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "image/svg+xml", contentType)
+		assert.True(t, strings.HasPrefix(string(image), "<svg"))
+	}
+
+	image, _, err = controller.GetPaymentQRCode(transaction, "", "gif")
+
+	assert.Error(t, err)
+	assert.Nil(t, image)
+}
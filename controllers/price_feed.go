@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const RateSourcePriceFeed = "priceFeed"
+
+// Subset of Chainlink AggregatorV3Interface.
+const priceFeedABI = `[
+	{"constant": true, "inputs": [], "name": "decimals", "outputs": [{"name": "", "type": "uint8"}], "stateMutability": "view", "type": "function"},
+	{"constant": true, "inputs": [], "name": "latestRoundData", "outputs": [
+		{"name": "roundId", "type": "uint80"},
+		{"name": "answer", "type": "int256"},
+		{"name": "startedAt", "type": "uint256"},
+		{"name": "updatedAt", "type": "uint256"},
+		{"name": "answeredInRound", "type": "uint80"}
+	], "stateMutability": "view", "type": "function"}
+]`
+
+const maxPriceFeedDecimals = 36
+
+// PriceFeedRateSource reads the rate from a Chainlink-style aggregator contract.
+// ETH/BTC feeds quote BTC per ETH and must be inverted.
+type PriceFeedRateSource struct {
+	InfuraController
+	feedABI          abi.ABI
+	feedAddress      common.Address
+	maxAge           time.Duration
+	expectedDecimals uint8
+	isInverted       bool
+}
+
+type priceFeedRound struct {
+	RoundId         *big.Int
+	Answer          *big.Int
+	StartedAt       *big.Int
+	UpdatedAt       *big.Int
+	AnsweredInRound *big.Int
+}
+
+// MakePriceFeedRateSource creates the price feed source. The decimals are not checked if expectedDecimals is zero.
+func MakePriceFeedRateSource(
+	infuraController InfuraController,
+	feedAddress string,
+	maxAge time.Duration,
+	expectedDecimals uint8,
+	isInverted bool,
+) (*PriceFeedRateSource, error) {
+	feedABI, err := abi.JSON(strings.NewReader(priceFeedABI))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !common.IsHexAddress(feedAddress) {
+		return nil, errors.New("invalid price feed address")
+	}
+
+	if maxAge <= 0 {
+		return nil, errors.New("price feed max age must be positive")
+	}
+
+	return &PriceFeedRateSource{
+		InfuraController: infuraController,
+		feedABI:          feedABI,
+		feedAddress:      common.HexToAddress(feedAddress),
+		maxAge:           maxAge,
+		expectedDecimals: expectedDecimals,
+		isInverted:       isInverted,
+	}, nil
+}
+
+func (source PriceFeedRateSource) Name() string {
+	return RateSourcePriceFeed
+}
+
+func (source PriceFeedRateSource) getDecimals() (uint8, error) {
+	var decimals uint8
+
+	if err := source.InfuraController.retrieveParameter(&decimals, "decimals", source.feedABI, source.feedAddress); err != nil {
+		return 0, err
+	}
+
+	return decimals, nil
+}
+
+func (source PriceFeedRateSource) getLatestRound() (*priceFeedRound, error) {
+	round := new(priceFeedRound)
+
+	if err := source.InfuraController.retrieveParameter(round, "latestRoundData", source.feedABI, source.feedAddress); err != nil {
+		return nil, err
+	}
+
+	return round, nil
+}
+
+func (source PriceFeedRateSource) GetRate() (float64, error) {
+	decimals, err := source.getDecimals()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if decimals > maxPriceFeedDecimals || (source.expectedDecimals != 0 && decimals != source.expectedDecimals) {
+		return 0, fmt.Errorf("unexpected price feed decimals %d", decimals)
+	}
+
+	round, err := source.getLatestRound()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if round.Answer.Sign() != 1 {
+		return 0, errors.New("price feed answer must be positive")
+	}
+
+	if round.AnsweredInRound.Cmp(round.RoundId) == -1 {
+		return 0, errors.New("price feed answer is carried over from a previous round")
+	}
+
+	updatedAt := time.Unix(round.UpdatedAt.Int64(), 0)
+
+	if round.UpdatedAt.Sign() == 0 || time.Since(updatedAt) > source.maxAge {
+		return 0, fmt.Errorf("price feed answer is stale, last updated at %s", updatedAt.UTC())
+	}
+
+	rate := new(big.Rat).SetFrac(round.Answer, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+
+	if source.isInverted {
+		rate.Inv(rate)
+	}
+
+	value, _ := rate.Float64()
+
+	return value, nil
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func registerPriceFeedResponder(t *testing.T, source *PriceFeedRateSource, decimals uint8, answer *big.Int, updatedAt time.Time, answeredInRound int64) {
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		func(request *http.Request) (*http.Response, error) {
+			defer request.Body.Close()
+
+			requestBodyBytes, err := ioutil.ReadAll(request.Body)
+
+			if err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			requestBody := new(requestPayload)
+
+			if err := json.Unmarshal(requestBodyBytes, requestBody); err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			data := requestBody.Params[0].(map[string]interface{})["data"].(string)
+
+			decimalsData, _ := source.feedABI.Pack("decimals")
+			latestRoundData, _ := source.feedABI.Pack("latestRoundData")
+
+			var result []byte
+
+			switch data {
+			case hexutil.Bytes(decimalsData).String():
+				result, err = source.feedABI.Methods["decimals"].Outputs.Pack(decimals)
+			case hexutil.Bytes(latestRoundData).String():
+				result, err = source.feedABI.Methods["latestRoundData"].Outputs.Pack(
+					big.NewInt(10),
+					answer,
+					big.NewInt(updatedAt.Unix()),
+					big.NewInt(updatedAt.Unix()),
+					big.NewInt(answeredInRound),
+				)
+			default:
+				err = errors.New("unexpected call")
+			}
+
+			if !assert.NoError(t, err) {
+				return nil, err
+			}
+
+			return httpmock.NewStringResponse(
+				http.StatusOK,
+				`{"jsonrpc": "2.0", "result": "`+hexutil.Bytes(result).String()+`"}`,
+			), nil
+		},
+	)
+}
+
+func TestMakePriceFeedRateSource(t *testing.T) {
+	source, err := MakePriceFeedRateSource(testInfuraController(), "0xAc559F25B1619171CbC396a50854A3240b6A4e99", time.Hour, 8, true)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, RateSourcePriceFeed, source.Name())
+		assert.Equal(t, "0xAc559F25B1619171CbC396a50854A3240b6A4e99", source.feedAddress.Hex())
+	}
+
+	_, err = MakePriceFeedRateSource(testInfuraController(), "PRICE_FEED_ADDRESS", time.Hour, 8, true)
+
+	assert.Error(t, err)
+
+	_, err = MakePriceFeedRateSource(testInfuraController(), "0xAc559F25B1619171CbC396a50854A3240b6A4e99", 0, 8, true)
+
+	assert.Error(t, err)
+}
+
+func TestPriceFeedRateSource_GetRate(t *testing.T) {
+	source, err := MakePriceFeedRateSource(testInfuraController(), "0xAc559F25B1619171CbC396a50854A3240b6A4e99", time.Hour, 8, true)
+
+	if assert.NoError(t, err) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		// 0.05 BTC per ETH
+		registerPriceFeedResponder(t, source, 8, big.NewInt(5000000), time.Now().Add(-time.Minute), 10)
+
+		rate, err := source.GetRate()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, float64(20), rate)
+		}
+
+		source.isInverted = false
+
+		rate, err = source.GetRate()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0.05, rate)
+		}
+	}
+}
+
+func TestPriceFeedRateSource_GetRate_Fail(t *testing.T) {
+	source, err := MakePriceFeedRateSource(testInfuraController(), "0xAc559F25B1619171CbC396a50854A3240b6A4e99", time.Hour, 8, true)
+
+	if assert.NoError(t, err) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		registerPriceFeedResponder(t, source, 18, big.NewInt(5000000), time.Now(), 10)
+
+		_, err := source.GetRate()
+
+		assert.EqualError(t, err, "unexpected price feed decimals 18")
+
+		registerPriceFeedResponder(t, source, 8, big.NewInt(5000000), time.Now().Add(-2*time.Hour), 10)
+
+		_, err = source.GetRate()
+
+		assert.Error(t, err)
+
+		registerPriceFeedResponder(t, source, 8, big.NewInt(0), time.Now(), 10)
+
+		_, err = source.GetRate()
+
+		assert.EqualError(t, err, "price feed answer must be positive")
+
+		registerPriceFeedResponder(t, source, 8, big.NewInt(5000000), time.Now(), 9)
+
+		_, err = source.GetRate()
+
+		assert.EqualError(t, err, "price feed answer is carried over from a previous round")
+
+		httpmock.RegisterResponder(http.MethodPost, testRPCEndpoint, httpmock.NewErrorResponder(errors.New("infura malfunction")))
+
+		_, err = source.GetRate()
+
+		assert.Error(t, err)
+	}
+}
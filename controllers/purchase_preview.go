@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"math/big"
+	"time"
+
+	"MCW-btc-module/helpers"
+	"MCW-btc-module/model"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PurchasePreview is what a BTC amount would buy if the deposit confirmed now.
+type PurchasePreview struct {
+	Phase             string        `json:"phase"`
+	Rate              float64       `json:"rate"`
+	TokenRate         string        `json:"tokenRate"`
+	WeiAmount         *model.BigInt `json:"weiAmount"`
+	AcceptedWei       *model.BigInt `json:"acceptedWei"`
+	RefundedWei       *model.BigInt `json:"refundedWei"`
+	IsBelowMinimum    bool          `json:"isBelowMinimum"`
+	BaseTokens        *model.BigInt `json:"baseTokens"`
+	BonusPercent      int64         `json:"bonusPercent"`
+	BonusTokens       *model.BigInt `json:"bonusTokens"`
+	MinimalInvestment *model.BigInt `json:"minimalInvestment"`
+	MaximalInvestment *model.BigInt `json:"maximalInvestment"`
+	Investment        *model.BigInt `json:"investment"`
+}
+
+// PreviewPurchase computes the purchase of the BTC amount, e.g. "0.5", from the live crowdsale state.
+// The investor's previous investment is taken into account if the investor is given.
+func (controller ExchangeController) PreviewPurchase(amount string, investor *common.Address) (*PurchasePreview, error) {
+	satoshis, err := helpers.BTCToSatoshis(amount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := controller.getExchangeRate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The crowdsale state is read in one batch, so the preview can't mix two states.
+	state, err := controller.TokenManagementController.getCrowdsaleState(investor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	phase, err := state.phase()
+
+	if err != nil {
+		return nil, err
+	}
+
+	tokenRate, err := state.tokenRate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	minimum, maximum, investment := state.minimalInvestment, state.maximalInvestment, big.NewInt(0)
+
+	if investor != nil {
+		investment = state.investment()
+	}
+
+	weiAmount := helpers.SatoshisToWei(satoshis, rate)
+
+	preview := &PurchasePreview{
+		Phase:             phase,
+		Rate:              rate,
+		TokenRate:         tokenRate.String(),
+		WeiAmount:         model.NewBigInt(weiAmount),
+		AcceptedWei:       model.NewBigInt(big.NewInt(0)),
+		RefundedWei:       model.NewBigInt(big.NewInt(0)),
+		IsBelowMinimum:    weiAmount.Cmp(minimum) == -1,
+		BaseTokens:        model.NewBigInt(big.NewInt(0)),
+		BonusTokens:       model.NewBigInt(big.NewInt(0)),
+		MinimalInvestment: model.NewBigInt(minimum),
+		MaximalInvestment: model.NewBigInt(maximum),
+		Investment:        model.NewBigInt(investment),
+	}
+
+	// Deposits below the minimum are held rather than sold or refunded.
+	if preview.IsBelowMinimum {
+		return preview, nil
+	}
+
+	acceptedWei, refundedWei := applyInvestmentLimits(weiAmount, minimum, maximum, investment)
+
+	baseTokens := big.NewInt(0)
+
+	if acceptedWei.Sign() == 1 {
+		cappedWei, cappedTokens := state.capByTokensLeft(acceptedWei, tokenRate)
+
+		refundedWei = big.NewInt(0).Add(refundedWei, big.NewInt(0).Sub(acceptedWei, cappedWei))
+		acceptedWei, baseTokens = cappedWei, cappedTokens
+	}
+
+	preview.AcceptedWei = model.NewBigInt(acceptedWei)
+	preview.RefundedWei = model.NewBigInt(refundedWei)
+	preview.BaseTokens = model.NewBigInt(baseTokens)
+
+	if phase == PhaseICO && baseTokens.Sign() == 1 {
+		bonusPercent, bonusTokens, err := controller.TokenManagementController.GetICOBonus(acceptedWei, baseTokens, time.Now())
+
+		if err != nil {
+			return nil, err
+		}
+
+		preview.BonusPercent = bonusPercent
+		preview.BonusTokens = model.NewBigInt(bonusTokens)
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func TestExchangeController_PreviewPurchase(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	assert.NoError(t, err)
+
+	tokenManagementController, err := MakeTokenManagementController(testInfuraController(), "", "")
+
+	assert.NoError(t, err)
+
+	rateOracleController, err := MakeRateOracleController([]RateSource{BinanceRateSource{binanceRateEndpoint}}, 0.05, 1)
+
+	assert.NoError(t, err)
+
+	controller, err := MakeExchangeController(
+		MonitoringController{},
+		*tokenManagementController,
+		rateOracleController,
+		QuoteController{},
+		RateCircuitBreakerController{},
+		ReceiptController{},
+		GasBumpingController{},
+		db,
+		"tpubDAbGZM7PHnNp75QbARzDM7id7zpBcxKH9EX7VFE2Pr15EuWQEdRzSZSB4fhnHBxeLyzZB6QnhewQQhdkRHx6wCow3iTj6BXfwGsj8RevWoC",
+		true,
+	)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ether := big.NewInt(1000000000000000000)
+	investor := common.HexToAddress("0x123")
+	investment := big.NewInt(0)
+	hugeAmount := big.NewInt(0).Mul(ether, ether)
+
+	results := map[string]*big.Int{}
+
+	pack := func(name string, arguments ...interface{}) string {
+		data, err := controller.crowdsaleContractABI.Pack(name, arguments...)
+		assert.NoError(t, err)
+		return hexutil.Bytes(data).String()
+	}
+
+	results[pack("isPreIco")] = big.NewInt(0)
+	results[pack("isIco")] = big.NewInt(1)
+	results[pack("icoTokenRate")] = big.NewInt(35868)
+	results[pack("icoTokenRateNegativeDecimals")] = big.NewInt(8)
+	results[pack("tokensRemainingIco")] = hugeAmount
+	results[pack("MINIMAL_INVESTMENT")] = big.NewInt(0).Div(ether, big.NewInt(10))
+	results[pack("MAXIMAL_INVESTMENT")] = big.NewInt(0).Mul(big.NewInt(5), ether)
+	results[pack("getIcoInvestment", investor)] = investment
+	results[pack("getPreIcoInvestment", investor)] = big.NewInt(0)
+	results[pack("preIcoTokenRate")] = big.NewInt(0)
+	results[pack("preIcoTokenRateNegativeDecimals")] = big.NewInt(0)
+	results[pack("tokensRemainingPreIco")] = big.NewInt(0)
+	results[pack("compaignAllocationAndBonusRemainingTokens")] = hugeAmount
+	results[pack("icoTenPercentBonusEnded")] = big.NewInt(time.Now().Add(24 * time.Hour).Unix())
+	results[pack("icoFivePercentBonusEnded")] = big.NewInt(time.Now().Add(48 * time.Hour).Unix())
+	results[pack("MINIMAL_TEN_PERCENT_BONUS_BY_VALUE")] = big.NewInt(0).Div(big.NewInt(0).Mul(big.NewInt(35), ether), big.NewInt(10))
+	results[pack("MINIMAL_FIVE_PERCENT_BONUS_BY_VALUE")] = big.NewInt(0).Div(big.NewInt(0).Mul(big.NewInt(25), ether), big.NewInt(10))
+
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		binanceRateEndpoint,
+		httpmock.NewStringResponder(http.StatusOK, `{"symbol": "ETHBTC", "price": "0.1"}`),
+	)
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		batchResponder(func(request *http.Request) (*http.Response, error) {
+			defer request.Body.Close()
+
+			requestBodyBytes, err := ioutil.ReadAll(request.Body)
+
+			if err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			requestBody := new(requestPayload)
+
+			if err := json.Unmarshal(requestBodyBytes, requestBody); err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			data := requestBody.Params[0].(map[string]interface{})["data"].(string)
+
+			result, ok := results[data]
+
+			if !ok {
+				return nil, errors.New("unexpected call")
+			}
+
+			return httpmock.NewStringResponse(
+				http.StatusOK,
+				`{"jsonrpc": "2.0", "result": "`+common.BigToHash(result).Hex()+`"}`,
+			), nil
+		}),
+	)
+
+	// 0.4 BTC at 10 ETH per BTC: ten percent time bonus and ten percent value bonus.
+	preview, err := controller.PreviewPurchase("0.4", nil)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, PhaseICO, preview.Phase)
+		assert.Equal(t, float64(10), preview.Rate)
+		assert.Equal(t, "35868e-8", preview.TokenRate)
+		assert.Equal(t, "4000000000000000000", preview.WeiAmount.String())
+		assert.Equal(t, "4000000000000000000", preview.AcceptedWei.String())
+		assert.Equal(t, "0", preview.RefundedWei.String())
+		assert.Equal(t, "11152001784320200000000", preview.BaseTokens.String())
+		assert.Equal(t, int64(20), preview.BonusPercent)
+		assert.Equal(t, "2230400356864040000000", preview.BonusTokens.String())
+		assert.Equal(t, "0", preview.Investment.String())
+	}
+
+	investment.Mul(big.NewInt(2), ether)
+
+	// 2 ether are accepted on top of the previous investment, which only passes the five percent value bonus.
+	preview, err = controller.PreviewPurchase("0.4", &investor)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "3000000000000000000", preview.AcceptedWei.String())
+		assert.Equal(t, "1000000000000000000", preview.RefundedWei.String())
+		assert.Equal(t, int64(15), preview.BonusPercent)
+		assert.Equal(t, "2000000000000000000", preview.Investment.String())
+	}
+
+	preview, err = controller.PreviewPurchase("0.001", nil)
+
+	if assert.NoError(t, err) {
+		assert.True(t, preview.IsBelowMinimum)
+		assert.Equal(t, "0", preview.BaseTokens.String())
+	}
+
+	_, err = controller.PreviewPurchase("abc", nil)
+
+	assert.EqualError(t, err, "invalid bitcoin amount")
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"MCW-btc-module/model"
+)
+
+const (
+	// QuotePolicyRequote makes the purchase at the rates current when the deposit arrives.
+	QuotePolicyRequote = "requote"
+	// QuotePolicyHold leaves the purchase for an operator to review.
+	QuotePolicyHold = "hold"
+	// QuotePolicyRefund routes the whole deposit to refund.
+	QuotePolicyRefund = "refund"
+)
+
+var ErrQuoteExpired = errors.New("rate quote expired")
+
+type QuoteController struct {
+	secret        []byte
+	validity      time.Duration
+	expiredPolicy string
+}
+
+func MakeQuoteController(secret string, validity time.Duration, expiredPolicy string) (QuoteController, error) {
+	if secret == "" {
+		return QuoteController{}, errors.New("quote secret must be set")
+	}
+
+	if validity <= 0 {
+		return QuoteController{}, errors.New("quote validity must be positive")
+	}
+
+	switch expiredPolicy {
+	case QuotePolicyRequote, QuotePolicyHold, QuotePolicyRefund:
+	default:
+		return QuoteController{}, fmt.Errorf("unknown expired quote policy '%s'", expiredPolicy)
+	}
+
+	return QuoteController{
+		secret:        []byte(secret),
+		validity:      validity,
+		expiredPolicy: expiredPolicy,
+	}, nil
+}
+
+func (controller QuoteController) signQuote(transaction *model.BTCTransaction) string {
+	var expiresAt int64
+
+	if transaction.QuoteExpiresAt != nil {
+		expiresAt = transaction.QuoteExpiresAt.Unix()
+	}
+
+	mac := hmac.New(sha256.New, controller.secret)
+
+	fmt.Fprintf(
+		mac,
+		"%s|%s|%s|%s|%s|%s|%d",
+		transaction.EthereumAddress,
+		transaction.BitcoinAddress,
+		transaction.PaymentHash,
+		strconv.FormatFloat(transaction.QuoteRate, 'g', -1, 64),
+		transaction.QuoteTokenRate,
+		transaction.QuotePhase,
+		expiresAt,
+	)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IssueQuote locks the rates on the transaction until the quote expires.
+func (controller QuoteController) IssueQuote(transaction *model.BTCTransaction, rate float64, tokenRate *TokenRate, phase string) {
+	expiresAt := time.Now().Add(controller.validity).UTC()
+
+	transaction.QuoteRate = rate
+	transaction.QuoteTokenRate = tokenRate.String()
+	transaction.QuotePhase = phase
+	transaction.QuoteExpiresAt = &expiresAt
+	transaction.QuoteSignature = controller.signQuote(transaction)
+}
+
+func (controller QuoteController) VerifyQuote(transaction *model.BTCTransaction) error {
+	if transaction.QuoteSignature == "" {
+		return errors.New("transaction has no rate quote")
+	}
+
+	signature, err := hex.DecodeString(transaction.QuoteSignature)
+
+	if err != nil {
+		return err
+	}
+
+	expectedSignature, _ := hex.DecodeString(controller.signQuote(transaction))
+
+	if !hmac.Equal(signature, expectedSignature) {
+		return errors.New("invalid rate quote signature")
+	}
+
+	return nil
+}
+
+// IsQuoteHonored checks whether the purchase can still be made at the quoted rates.
+func (controller QuoteController) IsQuoteHonored(transaction *model.BTCTransaction, phase string, now time.Time) bool {
+	if controller.VerifyQuote(transaction) != nil {
+		return false
+	}
+
+	return transaction.QuotePhase == phase && now.Before(*transaction.QuoteExpiresAt)
+}
+
+func (controller QuoteController) IsQuoteExpired(transaction *model.BTCTransaction, now time.Time) bool {
+	return transaction.QuoteExpiresAt == nil || !now.Before(*transaction.QuoteExpiresAt)
+}
+
+func (controller QuoteController) getQuotedRates(transaction *model.BTCTransaction) (float64, *TokenRate, error) {
+	tokenRate, err := ParseTokenRate(transaction.QuoteTokenRate)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return transaction.QuoteRate, tokenRate, nil
+}
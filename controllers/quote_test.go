@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"MCW-btc-module/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeQuoteController(t *testing.T) {
+	controller, err := MakeQuoteController("secret", time.Hour, QuotePolicyHold)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte("secret"), controller.secret)
+		assert.Equal(t, time.Hour, controller.validity)
+		assert.Equal(t, QuotePolicyHold, controller.expiredPolicy)
+	}
+
+	_, err = MakeQuoteController("", time.Hour, QuotePolicyHold)
+
+	assert.Error(t, err)
+
+	_, err = MakeQuoteController("secret", 0, QuotePolicyHold)
+
+	assert.Error(t, err)
+
+	_, err = MakeQuoteController("secret", time.Hour, "ignore")
+
+	assert.EqualError(t, err, "unknown expired quote policy 'ignore'")
+}
+
+func TestQuoteController_IssueQuote(t *testing.T) {
+	controller, _ := MakeQuoteController("secret", time.Hour, QuotePolicyRequote)
+
+	transaction := &model.BTCTransaction{
+		EthereumAddress: "0x5aeda56215b167893e80b4fe645ba6d5bab767de",
+		BitcoinAddress:  "mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt",
+	}
+
+	controller.IssueQuote(transaction, 10, &TokenRate{big.NewInt(17934), big.NewInt(8)}, PhasePreICO)
+
+	assert.Equal(t, float64(10), transaction.QuoteRate)
+	assert.Equal(t, "17934e-8", transaction.QuoteTokenRate)
+	assert.Equal(t, PhasePreICO, transaction.QuotePhase)
+	assert.NoError(t, controller.VerifyQuote(transaction))
+
+	rate, tokenRate, err := controller.getQuotedRates(transaction)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, float64(10), rate)
+		assert.Equal(t, "17934e-8", tokenRate.String())
+	}
+
+	now := time.Now()
+
+	assert.True(t, controller.IsQuoteHonored(transaction, PhasePreICO, now))
+	assert.False(t, controller.IsQuoteHonored(transaction, PhaseICO, now))
+	assert.False(t, controller.IsQuoteHonored(transaction, PhasePreICO, now.Add(2*time.Hour)))
+	assert.False(t, controller.IsQuoteExpired(transaction, now))
+	assert.True(t, controller.IsQuoteExpired(transaction, now.Add(2*time.Hour)))
+}
+
+func TestQuoteController_VerifyQuote(t *testing.T) {
+	controller, _ := MakeQuoteController("secret", time.Hour, QuotePolicyRequote)
+
+	transaction := &model.BTCTransaction{
+		EthereumAddress: "0x5aeda56215b167893e80b4fe645ba6d5bab767de",
+		BitcoinAddress:  "mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt",
+	}
+
+	assert.EqualError(t, controller.VerifyQuote(transaction), "transaction has no rate quote")
+
+	controller.IssueQuote(transaction, 10, &TokenRate{big.NewInt(17934), big.NewInt(8)}, PhasePreICO)
+
+	transaction.QuoteRate = 20
+
+	assert.EqualError(t, controller.VerifyQuote(transaction), "invalid rate quote signature")
+	assert.False(t, controller.IsQuoteHonored(transaction, PhasePreICO, time.Now()))
+
+	transaction.QuoteRate = 10
+
+	otherController, _ := MakeQuoteController("other secret", time.Hour, QuotePolicyRequote)
+
+	assert.EqualError(t, otherController.VerifyQuote(transaction), "invalid rate quote signature")
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// RateCircuitBreakerController holds purchases back from minting when the rate looks wrong.
+// Zero bounds are not checked.
+type RateCircuitBreakerController struct {
+	maxLastRateDeviation float64
+	maxMedianDeviation   float64
+	maxRateAge           time.Duration
+}
+
+func MakeRateCircuitBreakerController(
+	maxLastRateDeviation float64,
+	maxMedianDeviation float64,
+	maxRateAge time.Duration,
+) (RateCircuitBreakerController, error) {
+	if maxLastRateDeviation < 0 || maxMedianDeviation < 0 || maxRateAge < 0 {
+		return RateCircuitBreakerController{}, errors.New("rate circuit breaker bounds must not be negative")
+	}
+
+	return RateCircuitBreakerController{
+		maxLastRateDeviation: maxLastRateDeviation,
+		maxMedianDeviation:   maxMedianDeviation,
+		maxRateAge:           maxRateAge,
+	}, nil
+}
+
+// CheckRate returns the reason the rate must not be used. The last accepted rate is not checked if it's zero.
+// The median is taken of the rates the oracle accepted, so a source it rejected doesn't trip the breaker.
+func (controller RateCircuitBreakerController) CheckRate(
+	rate float64,
+	rateTime time.Time,
+	lastAcceptedRate float64,
+	acceptedRates map[string]float64,
+	now time.Time,
+) error {
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return fmt.Errorf("rate %v is not a positive number", rate)
+	}
+
+	if controller.maxRateAge != 0 && now.Sub(rateTime) > controller.maxRateAge {
+		return fmt.Errorf("rate %f from %s is stale", rate, rateTime.UTC())
+	}
+
+	if controller.maxLastRateDeviation != 0 && lastAcceptedRate > 0 &&
+		math.Abs(rate-lastAcceptedRate)/lastAcceptedRate > controller.maxLastRateDeviation {
+		return fmt.Errorf("rate %f deviates from last accepted rate %f", rate, lastAcceptedRate)
+	}
+
+	if controller.maxMedianDeviation == 0 {
+		return nil
+	}
+
+	if len(acceptedRates) == 0 {
+		return errors.New("no accepted rates to check the rate against")
+	}
+
+	if median := medianRate(rateValues(acceptedRates)); math.Abs(rate-median)/median > controller.maxMedianDeviation {
+		return fmt.Errorf("rate %f deviates from median rate %f", rate, median)
+	}
+
+	return nil
+}
+
+func (controller RateCircuitBreakerController) isMedianChecked() bool {
+	return controller.maxMedianDeviation != 0
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeRateCircuitBreakerController(t *testing.T) {
+	controller, err := MakeRateCircuitBreakerController(0.1, 0.05, time.Hour)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0.1, controller.maxLastRateDeviation)
+		assert.Equal(t, 0.05, controller.maxMedianDeviation)
+		assert.Equal(t, time.Hour, controller.maxRateAge)
+		assert.True(t, controller.isMedianChecked())
+	}
+
+	_, err = MakeRateCircuitBreakerController(-0.1, 0.05, time.Hour)
+
+	assert.Error(t, err)
+}
+
+func TestRateCircuitBreakerController_CheckRate(t *testing.T) {
+	controller, _ := MakeRateCircuitBreakerController(0.1, 0.05, time.Hour)
+
+	now := time.Now()
+
+	acceptedRates := map[string]float64{"a": 10.2, "b": 10.1, "c": 10.3}
+
+	assert.NoError(t, controller.CheckRate(10, now, 10.5, acceptedRates, now))
+	assert.NoError(t, controller.CheckRate(10, now, 0, acceptedRates, now))
+
+	assert.Error(t, controller.CheckRate(0, now, 10, acceptedRates, now))
+	assert.Error(t, controller.CheckRate(math.Inf(1), now, 10, acceptedRates, now))
+	assert.Error(t, controller.CheckRate(10, now.Add(-2*time.Hour), 10, acceptedRates, now))
+	assert.EqualError(t, controller.CheckRate(100, now, 10, map[string]float64{"a": 100}, now), "rate 100.000000 deviates from last accepted rate 10.000000")
+	assert.EqualError(t, controller.CheckRate(10, now, 10, map[string]float64{"a": 11}, now), "rate 10.000000 deviates from median rate 11.000000")
+	assert.Error(t, controller.CheckRate(10, now, 10, nil, now))
+
+	// Only the rates the oracle accepted are passed, so a source 10x off doesn't trip the breaker.
+	assert.NoError(t, controller.CheckRate(10, now, 10, map[string]float64{"a": 10, "c": 10.1}, now))
+}
+
+func TestRateCircuitBreakerController_CheckRate_Disabled(t *testing.T) {
+	controller := RateCircuitBreakerController{}
+
+	now := time.Now()
+
+	assert.False(t, controller.isMedianChecked())
+	assert.NoError(t, controller.CheckRate(100, now.Add(-48*time.Hour), 10, nil, now))
+	assert.Error(t, controller.CheckRate(-1, now, 10, nil, now))
+}
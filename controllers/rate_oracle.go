@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+type RateOracleController struct {
+	sources               []RateSource
+	fetchTimeout          time.Duration
+	maxDeviation          float64
+	minSources            int
+	reference             RateSource
+	maxReferenceDeviation float64
+}
+
+type rateQuote struct {
+	source string
+	rate   float64
+	err    error
+}
+
+// MakeRateOracleController combines the sources by median. Rates deviating from the median by more than
+// maxDeviation (e.g. 0.05 for 5%) are rejected, and at least minSources rates must remain.
+func MakeRateOracleController(sources []RateSource, maxDeviation float64, minSources int) (RateOracleController, error) {
+	if minSources < 1 {
+		minSources = 1
+	}
+
+	if len(sources) < minSources {
+		return RateOracleController{}, fmt.Errorf("at least %d rate sources required, %d configured", minSources, len(sources))
+	}
+
+	if maxDeviation <= 0 {
+		return RateOracleController{}, errors.New("maximal rate deviation must be positive")
+	}
+
+	return RateOracleController{
+		sources:      sources,
+		fetchTimeout: rateSourceTimeout,
+		maxDeviation: maxDeviation,
+		minSources:   minSources,
+	}, nil
+}
+
+// WithReference returns the controller which rejects aggregated rates deviating from the reference source by more than maxDeviation.
+func (controller RateOracleController) WithReference(reference RateSource, maxDeviation float64) RateOracleController {
+	controller.reference = reference
+	controller.maxReferenceDeviation = maxDeviation
+
+	return controller
+}
+
+func (controller RateOracleController) fetchRates() []rateQuote {
+	quotesChan := make(chan rateQuote, len(controller.sources))
+
+	for _, source := range controller.sources {
+		go func(source RateSource) {
+			rate, err := source.GetRate()
+			quotesChan <- rateQuote{source.Name(), rate, err}
+		}(source)
+	}
+
+	quotes := make([]rateQuote, 0, len(controller.sources))
+
+	// Sources which haven't answered in time are left out.
+	timeout := time.NewTimer(controller.fetchTimeout)
+	defer timeout.Stop()
+
+	for range controller.sources {
+		select {
+		case quote := <-quotesChan:
+			quotes = append(quotes, quote)
+		case <-timeout.C:
+			log.Printf("%d of %d rate sources timed out\n", len(controller.sources)-len(quotes), len(controller.sources))
+			return quotes
+		}
+	}
+
+	return quotes
+}
+
+// GetAcceptedRates returns the rates of the sources within the allowed deviation from the median, by source name.
+func (controller RateOracleController) GetAcceptedRates() (map[string]float64, error) {
+	rates := make([]float64, 0, len(controller.sources))
+	sourceRates := make(map[string]float64, len(controller.sources))
+
+	for _, quote := range controller.fetchRates() {
+		if quote.err != nil {
+			log.Printf("rate source %s failed: %s\n", quote.source, quote.err)
+			continue
+		}
+
+		rates = append(rates, quote.rate)
+		sourceRates[quote.source] = quote.rate
+	}
+
+	if len(rates) < controller.minSources {
+		return nil, fmt.Errorf("only %d of %d required rate sources responded", len(rates), controller.minSources)
+	}
+
+	median := medianRate(rates)
+
+	acceptedRates := make(map[string]float64, len(sourceRates))
+
+	for source, rate := range sourceRates {
+		if math.Abs(rate-median)/median <= controller.maxDeviation {
+			acceptedRates[source] = rate
+		} else {
+			log.Printf("rate %f rejected as deviating from median %f\n", rate, median)
+		}
+	}
+
+	if len(acceptedRates) < controller.minSources {
+		return nil, fmt.Errorf("only %d of %d required rates are within allowed deviation", len(acceptedRates), controller.minSources)
+	}
+
+	return acceptedRates, nil
+}
+
+// GetExchangeRate returns the BTC/ETH rate agreed on by the configured sources.
+func (controller RateOracleController) GetExchangeRate() (float64, error) {
+	acceptedRates, err := controller.GetAcceptedRates()
+
+	if err != nil {
+		return 0, err
+	}
+
+	rate := medianRate(rateValues(acceptedRates))
+
+	if controller.reference != nil {
+		referenceRate, err := controller.reference.GetRate()
+
+		if err != nil {
+			return 0, fmt.Errorf("reference rate source %s failed: %s", controller.reference.Name(), err)
+		}
+
+		if math.Abs(rate-referenceRate)/referenceRate > controller.maxReferenceDeviation {
+			return 0, fmt.Errorf("rate %f deviates from reference rate %f", rate, referenceRate)
+		}
+	}
+
+	return rate, nil
+}
+
+func rateValues(sourceRates map[string]float64) []float64 {
+	rates := make([]float64, 0, len(sourceRates))
+
+	for _, rate := range sourceRates {
+		rates = append(rates, rate)
+	}
+
+	return rates
+}
+
+func medianRate(rates []float64) float64 {
+	sorted := append([]float64{}, rates...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRateSource struct {
+	name string
+	rate float64
+	err  error
+}
+
+func (source mockRateSource) Name() string {
+	return source.name
+}
+
+func (source mockRateSource) GetRate() (float64, error) {
+	return source.rate, source.err
+}
+
+type slowRateSource struct {
+	mockRateSource
+	delay time.Duration
+}
+
+func (source slowRateSource) GetRate() (float64, error) {
+	time.Sleep(source.delay)
+
+	return source.mockRateSource.GetRate()
+}
+
+func TestMakeRateOracleController(t *testing.T) {
+	sources := []RateSource{mockRateSource{name: "a"}, mockRateSource{name: "b"}}
+
+	controller, err := MakeRateOracleController(sources, 0.05, 0)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, controller.minSources)
+	}
+
+	_, err = MakeRateOracleController(sources, 0.05, 3)
+
+	assert.Error(t, err)
+
+	_, err = MakeRateOracleController(sources, 0, 1)
+
+	assert.Error(t, err)
+}
+
+func TestRateOracleController_GetExchangeRate(t *testing.T) {
+	controller, err := MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 20, nil},
+		mockRateSource{"b", 20.4, nil},
+		mockRateSource{"c", 19.8, nil},
+		mockRateSource{"d", 200, nil},
+		mockRateSource{"e", 0, errors.New("unavailable")},
+	}, 0.05, 3)
+
+	if assert.NoError(t, err) {
+		rate, err := controller.GetExchangeRate()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, float64(20), rate)
+		}
+	}
+
+	controller, err = MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 20, nil},
+		mockRateSource{"b", 22, nil},
+	}, 0.01, 2)
+
+	if assert.NoError(t, err) {
+		_, err := controller.GetExchangeRate()
+
+		assert.Error(t, err)
+	}
+
+	controller, err = MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 20, nil},
+		mockRateSource{"b", 0, errors.New("unavailable")},
+	}, 0.05, 2)
+
+	if assert.NoError(t, err) {
+		_, err := controller.GetExchangeRate()
+
+		assert.Error(t, err)
+	}
+}
+
+func TestRateOracleController_GetExchangeRate_Timeout(t *testing.T) {
+	controller, err := MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 20, nil},
+		mockRateSource{"b", 20.2, nil},
+		slowRateSource{mockRateSource{"c", 19.8, nil}, time.Second},
+	}, 0.05, 2)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, rateSourceTimeout, controller.fetchTimeout)
+
+	controller.fetchTimeout = 50 * time.Millisecond
+
+	startedAt := time.Now()
+
+	rate, err := controller.GetExchangeRate()
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 20.1, rate)
+	}
+
+	assert.True(t, time.Since(startedAt) < time.Second)
+
+	controller.minSources = 3
+
+	_, err = controller.GetExchangeRate()
+
+	assert.EqualError(t, err, "only 2 of 3 required rate sources responded")
+}
+
+func TestRateOracleController_GetAcceptedRates(t *testing.T) {
+	controller, err := MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 20, nil},
+		mockRateSource{"b", 20.4, nil},
+		mockRateSource{"c", 200, nil},
+		mockRateSource{"d", 0, errors.New("unavailable")},
+	}, 0.05, 2)
+
+	if assert.NoError(t, err) {
+		acceptedRates, err := controller.GetAcceptedRates()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string]float64{"a": 20, "b": 20.4}, acceptedRates)
+		}
+	}
+
+	controller, err = MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 20, nil},
+		mockRateSource{"b", 20.4, nil},
+		mockRateSource{"c", 200, nil},
+	}, 0.05, 3)
+
+	if assert.NoError(t, err) {
+		_, err := controller.GetAcceptedRates()
+
+		assert.EqualError(t, err, "only 2 of 3 required rates are within allowed deviation")
+	}
+
+	controller, err = MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 20, nil},
+		mockRateSource{"b", 0, errors.New("unavailable")},
+	}, 0.05, 2)
+
+	if assert.NoError(t, err) {
+		_, err := controller.GetAcceptedRates()
+
+		assert.EqualError(t, err, "only 1 of 2 required rate sources responded")
+	}
+}
+
+func TestMedianRate(t *testing.T) {
+	assert.Equal(t, float64(2), medianRate([]float64{3, 1, 2}))
+	assert.Equal(t, float64(2.5), medianRate([]float64{4, 1, 3, 2}))
+}
+
+func TestRateOracleController_WithReference(t *testing.T) {
+	controller, err := MakeRateOracleController([]RateSource{
+		mockRateSource{"a", 20, nil},
+		mockRateSource{"b", 20.2, nil},
+	}, 0.05, 2)
+
+	if assert.NoError(t, err) {
+		rate, err := controller.WithReference(mockRateSource{"feed", 20.5, nil}, 0.03).GetExchangeRate()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, 20.1, rate)
+		}
+
+		_, err = controller.WithReference(mockRateSource{"feed", 25, nil}, 0.03).GetExchangeRate()
+
+		assert.Error(t, err)
+
+		_, err = controller.WithReference(mockRateSource{"feed", 0, errors.New("stale")}, 0.03).GetExchangeRate()
+
+		assert.Error(t, err)
+	}
+}
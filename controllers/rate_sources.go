@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"MCW-btc-module/helpers"
+)
+
+// RateSource provides the BTC/ETH rate, i.e. the amount of ETH one BTC is worth.
+type RateSource interface {
+	Name() string
+	GetRate() (float64, error)
+}
+
+const (
+	RateSourceKraken   = "kraken"
+	RateSourceCoinbase = "coinbase"
+	RateSourceBitstamp = "bitstamp"
+	RateSourceBinance  = "binance"
+)
+
+const (
+	krakenRateEndpoint   = "https://api.kraken.com/0/public/Ticker?pair=ETHXBT"
+	coinbaseRateEndpoint = "https://api.coinbase.com/v2/exchange-rates?currency=BTC"
+	bitstampRateEndpoint = "https://www.bitstamp.net/api/v2/ticker/ethbtc/"
+	binanceRateEndpoint  = "https://api.binance.com/api/v3/ticker/price?symbol=ETHBTC"
+)
+
+// rateSourceTimeout bounds every fetch of a rate, so a slow source is skipped rather than stalling quotes and purchases.
+const rateSourceTimeout = 10 * time.Second
+
+var rateSourceClient = &http.Client{Timeout: rateSourceTimeout}
+
+// MakeRateSource creates the rate source by its name. The default endpoint is used if the endpoint is empty.
+func MakeRateSource(name string, endpoint string) (RateSource, error) {
+	switch name {
+	case RateSourceKraken:
+		return KrakenRateSource{defaultEndpoint(endpoint, krakenRateEndpoint)}, nil
+	case RateSourceCoinbase:
+		return CoinbaseRateSource{defaultEndpoint(endpoint, coinbaseRateEndpoint)}, nil
+	case RateSourceBitstamp:
+		return BitstampRateSource{defaultEndpoint(endpoint, bitstampRateEndpoint)}, nil
+	case RateSourceBinance:
+		return BinanceRateSource{defaultEndpoint(endpoint, binanceRateEndpoint)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown rate source '%s'", name)
+}
+
+func defaultEndpoint(endpoint string, fallback string) string {
+	if endpoint == "" {
+		return fallback
+	}
+
+	return endpoint
+}
+
+func getRateSourceResponse(endpoint string, response interface{}) error {
+	status, responseBytes, err := helpers.RequestWithClient(rateSourceClient, http.MethodGet, endpoint, helpers.Headers{}, []byte{})
+
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("rate source responded with status %d", status)
+	}
+
+	return json.Unmarshal(responseBytes, response)
+}
+
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if rate <= 0 {
+		return 0, errors.New("rate must be positive")
+	}
+
+	return rate, nil
+}
+
+// parseInvertedRate is used for ETH/BTC pairs, which are quoted in BTC per ETH.
+func parseInvertedRate(value string) (float64, error) {
+	rate, err := parseRate(value)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return 1 / rate, nil
+}
+
+type KrakenRateSource struct {
+	endpoint string
+}
+
+func (source KrakenRateSource) Name() string {
+	return RateSourceKraken
+}
+
+func (source KrakenRateSource) GetRate() (float64, error) {
+	type KrakenResponse struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			LastTrade []string `json:"c"`
+		} `json:"result"`
+	}
+
+	response := new(KrakenResponse)
+
+	if err := getRateSourceResponse(source.endpoint, response); err != nil {
+		return 0, err
+	}
+
+	if len(response.Error) > 0 {
+		return 0, errors.New(response.Error[0])
+	}
+
+	for _, ticker := range response.Result {
+		if len(ticker.LastTrade) == 0 {
+			break
+		}
+
+		return parseInvertedRate(ticker.LastTrade[0])
+	}
+
+	return 0, errors.New("kraken returned no ticker")
+}
+
+type CoinbaseRateSource struct {
+	endpoint string
+}
+
+func (source CoinbaseRateSource) Name() string {
+	return RateSourceCoinbase
+}
+
+func (source CoinbaseRateSource) GetRate() (float64, error) {
+	type CoinbaseResponse struct {
+		Data struct {
+			Currency string            `json:"currency"`
+			Rates    map[string]string `json:"rates"`
+		} `json:"data"`
+	}
+
+	response := new(CoinbaseResponse)
+
+	if err := getRateSourceResponse(source.endpoint, response); err != nil {
+		return 0, err
+	}
+
+	if response.Data.Currency != "BTC" {
+		return 0, errors.New("coinbase returned rates for wrong currency")
+	}
+
+	return parseRate(response.Data.Rates["ETH"])
+}
+
+type BitstampRateSource struct {
+	endpoint string
+}
+
+func (source BitstampRateSource) Name() string {
+	return RateSourceBitstamp
+}
+
+func (source BitstampRateSource) GetRate() (float64, error) {
+	type BitstampResponse struct {
+		Last string `json:"last"`
+	}
+
+	response := new(BitstampResponse)
+
+	if err := getRateSourceResponse(source.endpoint, response); err != nil {
+		return 0, err
+	}
+
+	return parseInvertedRate(response.Last)
+}
+
+type BinanceRateSource struct {
+	endpoint string
+}
+
+func (source BinanceRateSource) Name() string {
+	return RateSourceBinance
+}
+
+func (source BinanceRateSource) GetRate() (float64, error) {
+	type BinanceResponse struct {
+		Symbol string `json:"symbol"`
+		Price  string `json:"price"`
+	}
+
+	response := new(BinanceResponse)
+
+	if err := getRateSourceResponse(source.endpoint, response); err != nil {
+		return 0, err
+	}
+
+	return parseInvertedRate(response.Price)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func TestMakeRateSource(t *testing.T) {
+	source, err := MakeRateSource(RateSourceKraken, "")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, KrakenRateSource{krakenRateEndpoint}, source)
+	}
+
+	source, err = MakeRateSource(RateSourceBinance, "http://binance.test/price")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, BinanceRateSource{"http://binance.test/price"}, source)
+	}
+
+	source, err = MakeRateSource("shapeshift", "")
+
+	assert.Error(t, err)
+	assert.Nil(t, source)
+}
+
+func TestRateSources_GetRate(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, krakenRateEndpoint, httpmock.NewStringResponder(
+		http.StatusOK,
+		`{"error": [], "result": {"XETHXXBT": {"a": ["0.05010", "1", "1.000"], "c": ["0.05000", "0.1"]}}}`,
+	))
+	httpmock.RegisterResponder(http.MethodGet, coinbaseRateEndpoint, httpmock.NewStringResponder(
+		http.StatusOK,
+		`{"data": {"currency": "BTC", "rates": {"ETH": "20.5", "USD": "6500.00"}}}`,
+	))
+	httpmock.RegisterResponder(http.MethodGet, bitstampRateEndpoint, httpmock.NewStringResponder(
+		http.StatusOK,
+		`{"high": "0.051", "last": "0.04", "low": "0.049"}`,
+	))
+	httpmock.RegisterResponder(http.MethodGet, binanceRateEndpoint, httpmock.NewStringResponder(
+		http.StatusOK,
+		`{"symbol": "ETHBTC", "price": "0.08"}`,
+	))
+
+	expectedRates := map[string]float64{
+		RateSourceKraken:   20,
+		RateSourceCoinbase: 20.5,
+		RateSourceBitstamp: 25,
+		RateSourceBinance:  12.5,
+	}
+
+	for name, expectedRate := range expectedRates {
+		source, err := MakeRateSource(name, "")
+
+		if assert.NoError(t, err) {
+			rate, err := source.GetRate()
+
+			if assert.NoError(t, err, name) {
+				assert.Equal(t, expectedRate, rate, name)
+			}
+		}
+	}
+}
+
+func TestRateSources_GetRate_Fail(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, krakenRateEndpoint, httpmock.NewStringResponder(
+		http.StatusOK,
+		`{"error": ["EQuery:Unknown asset pair"]}`,
+	))
+	httpmock.RegisterResponder(http.MethodGet, coinbaseRateEndpoint, httpmock.NewStringResponder(
+		http.StatusOK,
+		`{"data": {"currency": "USD", "rates": {"ETH": "0.005"}}}`,
+	))
+	httpmock.RegisterResponder(http.MethodGet, bitstampRateEndpoint, httpmock.NewStringResponder(
+		http.StatusOK,
+		`{"last": "0"}`,
+	))
+	httpmock.RegisterResponder(http.MethodGet, binanceRateEndpoint, httpmock.NewStringResponder(
+		http.StatusTeapot,
+		`{"code": -1121, "msg": "Invalid symbol."}`,
+	))
+
+	for _, name := range []string{RateSourceKraken, RateSourceCoinbase, RateSourceBitstamp, RateSourceBinance} {
+		source, err := MakeRateSource(name, "")
+
+		if assert.NoError(t, err) {
+			_, err := source.GetRate()
+
+			assert.Error(t, err, name)
+		}
+	}
+
+	httpmock.RegisterResponder(http.MethodGet, binanceRateEndpoint, httpmock.NewErrorResponder(errors.New("binance malfunction")))
+
+	_, err := BinanceRateSource{binanceRateEndpoint}.GetRate()
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var ErrTransactionDropped = errors.New("transaction has been dropped")
+
+// TransactionReceipt is the part of the receipt the service records.
+type TransactionReceipt struct {
+	BlockNumber  uint64
+	GasUsed      uint64
+	IsSuccessful bool
+}
+
+// ReceiptController tracks sent transactions until they are buried under enough blocks.
+type ReceiptController struct {
+	InfuraController
+	confirmations uint64
+	pollInterval  time.Duration
+	timeout       time.Duration
+}
+
+func MakeReceiptController(
+	infuraController InfuraController,
+	confirmations uint64,
+	pollInterval time.Duration,
+	timeout time.Duration,
+) (ReceiptController, error) {
+	if pollInterval <= 0 || timeout <= 0 {
+		return ReceiptController{}, errors.New("receipt poll interval and timeout must be positive")
+	}
+
+	return ReceiptController{
+		InfuraController: infuraController,
+		confirmations:    confirmations,
+		pollInterval:     pollInterval,
+		timeout:          timeout,
+	}, nil
+}
+
+type rpcReceipt struct {
+	BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+	GasUsed     hexutil.Uint64  `json:"gasUsed"`
+	Status      *hexutil.Uint64 `json:"status"`
+}
+
+// getTransactionReceipt returns nil while the transaction is pending.
+func (controller ReceiptController) getTransactionReceipt(hash common.Hash) (*TransactionReceipt, error) {
+	result, err := controller.InfuraController.callInfuraRaw(makeRequestPayload(
+		"eth_getTransactionReceipt",
+		[]interface{}{hash},
+	))
+
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := new(rpcReceipt)
+
+	if err := json.Unmarshal(result, &receipt); err != nil {
+		return nil, err
+	}
+
+	if receipt == nil || receipt.BlockNumber == nil {
+		return nil, nil
+	}
+
+	if receipt.Status == nil {
+		return nil, errors.New("receipt has no status")
+	}
+
+	return &TransactionReceipt{
+		BlockNumber:  uint64(*receipt.BlockNumber),
+		GasUsed:      uint64(receipt.GasUsed),
+		IsSuccessful: *receipt.Status == 1,
+	}, nil
+}
+
+func (controller ReceiptController) getBlockNumber() (uint64, error) {
+	response, err := controller.InfuraController.callInfura(makeRequestPayload("eth_blockNumber", []interface{}{}))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return hexutil.DecodeUint64(response.Result)
+}
+
+// CheckReceipt returns the receipt of the mined transaction along with the number of its confirmations.
+// The mining block counts as the first confirmation.
+func (controller ReceiptController) CheckReceipt(hash common.Hash) (*TransactionReceipt, uint64, error) {
+	receipt, err := controller.getTransactionReceipt(hash)
+
+	if err != nil || receipt == nil {
+		return nil, 0, err
+	}
+
+	blockNumber, err := controller.getBlockNumber()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if blockNumber < receipt.BlockNumber {
+		return receipt, 0, nil
+	}
+
+	return receipt, blockNumber - receipt.BlockNumber + 1, nil
+}
+
+// CheckReceipts returns the receipt of whichever of the transactions replacing each other has been mined,
+// along with its hash and confirmations.
+func (controller ReceiptController) CheckReceipts(hashes []common.Hash) (*TransactionReceipt, common.Hash, uint64, error) {
+	var lastErr error
+
+	for _, hash := range hashes {
+		receipt, confirmations, err := controller.CheckReceipt(hash)
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if receipt != nil {
+			return receipt, hash, confirmations, nil
+		}
+	}
+
+	return nil, common.Hash{}, 0, lastErr
+}
+
+func (controller ReceiptController) IsConfirmed(confirmations uint64) bool {
+	return confirmations >= controller.confirmations
+}
+
+// isKnown tells whether the node knows the transaction, be it pending or mined.
+func (controller ReceiptController) isKnown(hash common.Hash) (bool, error) {
+	result, err := controller.InfuraController.callInfuraRaw(makeRequestPayload(
+		"eth_getTransactionByHash",
+		[]interface{}{hash},
+	))
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(result) > 0 && string(result) != "null", nil
+}
+
+// IsDropped tells whether the unmined transactions replacing each other, the first sent at sentAt, should be given up on.
+// They are only given up on once they have timed out, the node knows none of them and the nonce of the account
+// hasn't been used, so that none of them can be mined later. Otherwise they are tracked further.
+func (controller ReceiptController) IsDropped(
+	account common.Address,
+	nonce uint64,
+	hashes []common.Hash,
+	sentAt time.Time,
+	now time.Time,
+) (bool, error) {
+	if now.Sub(sentAt) <= controller.timeout {
+		return false, nil
+	}
+
+	for _, hash := range hashes {
+		isKnown, err := controller.isKnown(hash)
+
+		if err != nil || isKnown {
+			return false, err
+		}
+	}
+
+	pendingNonce, err := controller.InfuraController.getPendingNonce(account)
+
+	if err != nil {
+		return false, err
+	}
+
+	return pendingNonce <= nonce, nil
+}
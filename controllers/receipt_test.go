@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"MCW-btc-module/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+const testMintTransactionHash = "0x902912aeafe06a03ca95c70cad2e709c89e9b4f4a99aa6a0ae386408ae131b0f"
+
+func TestMakeReceiptController(t *testing.T) {
+	controller, err := MakeReceiptController(testInfuraController(), 12, time.Second, time.Hour)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint64(12), controller.confirmations)
+	}
+
+	_, err = MakeReceiptController(testInfuraController(), 12, 0, time.Hour)
+
+	assert.Error(t, err)
+}
+
+func TestReceiptController_CheckReceipt(t *testing.T) {
+	infuraController := testInfuraController()
+	controller, _ := MakeReceiptController(infuraController, 12, time.Second, time.Hour)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `null`,
+		"eth_blockNumber":           `"0x20"`,
+	})
+
+	receipt, confirmations, err := controller.CheckReceipt(common.HexToHash(testMintTransactionHash))
+
+	if assert.NoError(t, err) {
+		assert.Nil(t, receipt)
+		assert.Equal(t, uint64(0), confirmations)
+	}
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `{"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x1"}`,
+		"eth_blockNumber":           `"0x20"`,
+	})
+
+	receipt, confirmations, err = controller.CheckReceipt(common.HexToHash(testMintTransactionHash))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, &TransactionReceipt{BlockNumber: 27, GasUsed: 120000, IsSuccessful: true}, receipt)
+		assert.Equal(t, uint64(6), confirmations)
+	}
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `{"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x0"}`,
+		"eth_blockNumber":           `"0x1a"`,
+	})
+
+	receipt, confirmations, err = controller.CheckReceipt(common.HexToHash(testMintTransactionHash))
+
+	if assert.NoError(t, err) {
+		assert.False(t, receipt.IsSuccessful)
+		assert.Equal(t, uint64(0), confirmations)
+	}
+}
+
+func TestReceiptController_CheckReceipts(t *testing.T) {
+	infuraController := testInfuraController()
+	controller, _ := MakeReceiptController(infuraController, 6, time.Second, time.Hour)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `{"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x1"}`,
+		"eth_blockNumber":           `"0x20"`,
+	})
+
+	receipt, hash, confirmations, err := controller.CheckReceipts([]common.Hash{common.HexToHash(testMintTransactionHash)})
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint64(27), receipt.BlockNumber)
+		assert.Equal(t, common.HexToHash(testMintTransactionHash), hash)
+		assert.True(t, controller.IsConfirmed(confirmations))
+	}
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `null`,
+		"eth_blockNumber":           `"0x20"`,
+	})
+
+	receipt, _, _, err = controller.CheckReceipts([]common.Hash{common.HexToHash(testMintTransactionHash)})
+
+	if assert.NoError(t, err) {
+		assert.Nil(t, receipt)
+	}
+
+	assert.False(t, controller.IsConfirmed(5))
+}
+
+func TestReceiptController_IsDropped(t *testing.T) {
+	infuraController := testInfuraController()
+	controller, _ := MakeReceiptController(infuraController, 12, time.Second, time.Hour)
+	account := common.HexToAddress("0x123")
+	hashes := []common.Hash{common.HexToHash(testMintTransactionHash)}
+	now := time.Now()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionByHash": `null`,
+		"eth_getTransactionCount":  `"0x5"`,
+	})
+
+	isDropped, err := controller.IsDropped(account, 5, hashes, now, now.Add(time.Minute))
+
+	if assert.NoError(t, err) {
+		assert.False(t, isDropped)
+	}
+
+	isDropped, err = controller.IsDropped(account, 5, hashes, now, now.Add(2*time.Hour))
+
+	if assert.NoError(t, err) {
+		assert.True(t, isDropped)
+	}
+
+	// The nonce has been used by a transaction the service doesn't know of.
+	isDropped, err = controller.IsDropped(account, 4, hashes, now, now.Add(2*time.Hour))
+
+	if assert.NoError(t, err) {
+		assert.False(t, isDropped)
+	}
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionByHash": `{"hash": "` + testMintTransactionHash + `", "blockNumber": null}`,
+		"eth_getTransactionCount":  `"0x5"`,
+	})
+
+	isDropped, err = controller.IsDropped(account, 5, hashes, now, now.Add(2*time.Hour))
+
+	if assert.NoError(t, err) {
+		assert.False(t, isDropped)
+	}
+
+	registerFakeNode(infuraController, map[string]string{})
+
+	isDropped, err = controller.IsDropped(account, 5, hashes, now, now.Add(2*time.Hour))
+
+	assert.Error(t, err)
+	assert.False(t, isDropped)
+}
+
+func TestExchangeController_TrackMintTransaction(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.DropTableIfExists(model.BTCTransaction{})
+	db.AutoMigrate(model.BTCTransaction{})
+	db.DropTableIfExists(model.MintReplacement{})
+	db.AutoMigrate(model.MintReplacement{})
+
+	infuraController := testInfuraController()
+	receiptController, _ := MakeReceiptController(infuraController, 6, time.Millisecond, 50*time.Millisecond)
+
+	controller := ExchangeController{receiptController: receiptController, database: db}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `{"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x1"}`,
+		"eth_blockNumber":           `"0x20"`,
+	})
+
+	transaction := &model.BTCTransaction{MintTransactionHash: testMintTransactionHash, Status: model.TRANSACTION_STATUS_MINTING}
+
+	controller.trackMintTransaction(transaction)
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_SUCCESS), transaction.Status)
+	assert.Equal(t, uint64(27), transaction.MintBlockNumber)
+	assert.Equal(t, uint64(120000), transaction.MintGasUsed)
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `{"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x0"}`,
+		"eth_blockNumber":           `"0x20"`,
+	})
+
+	transaction = &model.BTCTransaction{MintTransactionHash: testMintTransactionHash, Status: model.TRANSACTION_STATUS_MINTING}
+
+	controller.trackMintTransaction(transaction)
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_MINT_FAILED), transaction.Status)
+	assert.Equal(t, "mint transaction reverted", transaction.Error)
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `null`,
+		"eth_blockNumber":           `"0x20"`,
+		"eth_getTransactionByHash":  `null`,
+		"eth_getTransactionCount":   `"0x5"`,
+	})
+
+	transaction = &model.BTCTransaction{
+		MintTransactionHash: testMintTransactionHash,
+		MintFrom:            "0x0000000000000000000000000000000000000123",
+		MintNonce:           5,
+		Status:              model.TRANSACTION_STATUS_MINTING,
+	}
+
+	controller.trackMintTransaction(transaction)
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_MINT_FAILED), transaction.Status)
+	assert.Equal(t, ErrTransactionDropped.Error(), transaction.Error)
+
+	storedTransaction := model.BTCTransaction{}
+
+	if assert.NoError(t, db.First(&storedTransaction, transaction.ID).Error) {
+		assert.Equal(t, int8(model.TRANSACTION_STATUS_MINT_FAILED), storedTransaction.Status)
+	}
+}
+
+func TestExchangeController_TrackMintTransaction_Pending(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.DropTableIfExists(model.BTCTransaction{})
+	db.AutoMigrate(model.BTCTransaction{})
+	db.DropTableIfExists(model.MintReplacement{})
+	db.AutoMigrate(model.MintReplacement{})
+
+	infuraController := testInfuraController()
+	receiptController, _ := MakeReceiptController(infuraController, 1, time.Millisecond, 10*time.Millisecond)
+
+	controller := ExchangeController{receiptController: receiptController, database: db}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// The mint is still in the pool after the timeout.
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `null`,
+		"eth_blockNumber":           `"0x20"`,
+		"eth_getTransactionByHash":  `{"hash": "` + testMintTransactionHash + `", "blockNumber": null}`,
+		"eth_getTransactionCount":   `"0x6"`,
+	})
+
+	transaction := &model.BTCTransaction{
+		MintTransactionHash: testMintTransactionHash,
+		MintFrom:            "0x0000000000000000000000000000000000000123",
+		MintNonce:           5,
+		Status:              model.TRANSACTION_STATUS_MINTING,
+	}
+
+	done := make(chan bool)
+
+	go func() {
+		controller.trackMintTransaction(transaction)
+		close(done)
+	}()
+
+	isDone := func() bool {
+		select {
+		case <-done:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}
+
+	assert.False(t, isDone())
+
+	// The node has forgotten the mint, but its nonce has been used, so it may still show up mined.
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `null`,
+		"eth_blockNumber":           `"0x20"`,
+		"eth_getTransactionByHash":  `null`,
+		"eth_getTransactionCount":   `"0x6"`,
+	})
+
+	assert.False(t, isDone())
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `{"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x1"}`,
+		"eth_blockNumber":           `"0x20"`,
+	})
+
+	if assert.True(t, isDone()) {
+		assert.Equal(t, int8(model.TRANSACTION_STATUS_SUCCESS), transaction.Status)
+	}
+}
+
+func TestExchangeController_ResumeMonitoring(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.DropTableIfExists(model.BTCTransaction{})
+	db.AutoMigrate(model.BTCTransaction{})
+	db.DropTableIfExists(model.MintReplacement{})
+	db.AutoMigrate(model.MintReplacement{})
+
+	infuraController := testInfuraController()
+	receiptController, _ := MakeReceiptController(infuraController, 6, time.Millisecond, time.Hour)
+
+	controller := ExchangeController{receiptController: receiptController, database: db}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerFakeNode(infuraController, map[string]string{
+		"eth_getTransactionReceipt": `{"blockNumber": "0x1b", "gasUsed": "0x1d4c0", "status": "0x1"}`,
+		"eth_blockNumber":           `"0x20"`,
+	})
+
+	// The mint was sent before the service stopped.
+	transaction := &model.BTCTransaction{MintTransactionHash: testMintTransactionHash, Status: model.TRANSACTION_STATUS_MINTING}
+
+	db.Create(transaction)
+
+	controller.ResumeMonitoring()
+
+	stored := model.BTCTransaction{}
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if db.First(&stored, transaction.ID); stored.Status != model.TRANSACTION_STATUS_MINTING {
+			break
+		}
+	}
+
+	assert.Equal(t, int8(model.TRANSACTION_STATUS_SUCCESS), stored.Status)
+	assert.Equal(t, uint64(27), stored.MintBlockNumber)
+}
@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"MCW-btc-module/helpers"
+
+	"golang.org/x/net/websocket"
+)
+
+// RPCEndpoint is an Ethereum JSON-RPC endpoint of a node or a provider, reached over HTTP(S), WebSocket or an IPC socket path.
+type RPCEndpoint struct {
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// rpcTransport posts either a single request or a batch of them.
+type rpcTransport interface {
+	post(payload interface{}, response interface{}) error
+}
+
+// rpcRequestTimeout bounds every request, so a hung endpoint is failed over like a failing one.
+const rpcRequestTimeout = 30 * time.Second
+
+type httpTransport struct {
+	endpoint RPCEndpoint
+	client   *http.Client
+}
+
+func (transport httpTransport) post(payload interface{}, response interface{}) error {
+	marshaledPayload, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	headers := helpers.Headers{"Content-Type": "application/json"}
+
+	for header, value := range transport.endpoint.Headers {
+		headers[header] = value
+	}
+
+	code, responseBody, err := helpers.RequestWithClient(transport.client, http.MethodPost, transport.endpoint.URL, headers, marshaledPayload)
+
+	if err != nil {
+		return err
+	}
+
+	// Rate limited and failing endpoints are given up on, JSON-RPC errors are returned in the response.
+	if code == http.StatusTooManyRequests || code >= http.StatusInternalServerError {
+		return fmt.Errorf("endpoint responded with status %d", code)
+	}
+
+	return json.Unmarshal(responseBody, response)
+}
+
+// websocketTransport sends one request at a time over the connection, which is reopened after failures.
+type websocketTransport struct {
+	endpoint   RPCEndpoint
+	mutex      sync.Mutex
+	connection *websocket.Conn
+	nextID     int
+}
+
+func (transport *websocketTransport) connect() (*websocket.Conn, error) {
+	if transport.connection != nil {
+		return transport.connection, nil
+	}
+
+	config, err := websocket.NewConfig(transport.endpoint.URL, "http://localhost/")
+
+	if err != nil {
+		return nil, err
+	}
+
+	for header, value := range transport.endpoint.Headers {
+		config.Header.Set(header, value)
+	}
+
+	connection, err := websocket.DialConfig(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transport.connection = connection
+
+	return connection, nil
+}
+
+func (transport *websocketTransport) disconnect() {
+	if transport.connection != nil {
+		transport.connection.Close()
+		transport.connection = nil
+	}
+}
+
+func (transport *websocketTransport) post(payload interface{}, response interface{}) error {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+
+	connection, err := transport.connect()
+
+	if err != nil {
+		return err
+	}
+
+	if err := connection.SetDeadline(time.Now().Add(rpcRequestTimeout)); err != nil {
+		transport.disconnect()
+		return err
+	}
+
+	// Batches keep the ids of their requests and are answered with arrays.
+	request, isSingle := payload.(requestPayload)
+
+	if isSingle {
+		transport.nextID++
+		request.ID = transport.nextID
+		payload = request
+	}
+
+	if err := websocket.JSON.Send(connection, payload); err != nil {
+		transport.disconnect()
+		return err
+	}
+
+	// Subscription notifications and late responses to abandoned requests are skipped.
+	for {
+		var message json.RawMessage
+
+		if err := websocket.JSON.Receive(connection, &message); err != nil {
+			transport.disconnect()
+			return err
+		}
+
+		if !isSingle {
+			if len(message) > 0 && message[0] == '[' {
+				return json.Unmarshal(message, response)
+			}
+
+			continue
+		}
+
+		var header struct {
+			ID *int `json:"id"`
+		}
+
+		if err := json.Unmarshal(message, &header); err != nil {
+			transport.disconnect()
+			return err
+		}
+
+		if header.ID != nil && *header.ID == request.ID {
+			return json.Unmarshal(message, response)
+		}
+	}
+}
+
+// ipcTransport opens a connection to the socket per request, e.g. to the clef.ipc of an external signer.
+type ipcTransport struct {
+	endpoint RPCEndpoint
+}
+
+func (transport ipcTransport) post(payload interface{}, response interface{}) error {
+	connection, err := net.DialTimeout("unix", transport.endpoint.URL, rpcRequestTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer connection.Close()
+
+	if err := connection.SetDeadline(time.Now().Add(rpcRequestTimeout)); err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(connection).Encode(payload); err != nil {
+		return err
+	}
+
+	return json.NewDecoder(connection).Decode(response)
+}
+
+func makeRPCTransport(endpoint RPCEndpoint) (rpcTransport, error) {
+	parsedURL, err := url.Parse(endpoint.URL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch parsedURL.Scheme {
+	case "http", "https":
+		return httpTransport{endpoint, &http.Client{Timeout: rpcRequestTimeout}}, nil
+	case "ws", "wss":
+		return &websocketTransport{endpoint: endpoint}, nil
+	case "":
+		if parsedURL.Path != "" {
+			return ipcTransport{endpoint}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported RPC endpoint scheme '%s'", parsedURL.Scheme)
+}
+
+// RPCClient sends requests to the endpoint which answered last, failing over to the others in order.
+type RPCClient struct {
+	transports []rpcTransport
+	current    int32
+}
+
+func MakeRPCClient(endpoints []RPCEndpoint) (*RPCClient, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no RPC endpoints configured")
+	}
+
+	transports := make([]rpcTransport, 0, len(endpoints))
+
+	for _, endpoint := range endpoints {
+		transport, err := makeRPCTransport(endpoint)
+
+		if err != nil {
+			return nil, err
+		}
+
+		transports = append(transports, transport)
+	}
+
+	return &RPCClient{transports: transports}, nil
+}
+
+func (client *RPCClient) post(payload interface{}, response interface{}) error {
+	_, err := client.postFailingOver(payload, response)
+
+	return err
+}
+
+// postFailingOver also returns the number of endpoints which failed before one answered. Any of them may have
+// received the payload before failing.
+func (client *RPCClient) postFailingOver(payload interface{}, response interface{}) (int, error) {
+	start := int(atomic.LoadInt32(&client.current))
+
+	var err error
+
+	for attempt := range client.transports {
+		index := (start + attempt) % len(client.transports)
+
+		// A failed attempt may have decoded part of its answer, so every attempt decodes into a fresh value.
+		answer := reflect.New(reflect.TypeOf(response).Elem())
+
+		if err = client.transports[index].post(payload, answer.Interface()); err == nil {
+			reflect.ValueOf(response).Elem().Set(answer.Elem())
+			atomic.StoreInt32(&client.current, int32(index))
+			return attempt, nil
+		}
+
+		// URLs aren't logged, since they may contain access tokens.
+		log.Println("RPC ENDPOINT", index, "FAILED:", err)
+	}
+
+	return len(client.transports), err
+}
@@ -0,0 +1,376 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+const testRPCEndpoint = "https://rpc.test/"
+
+func testInfuraController() InfuraController {
+	controller, _ := MakeRPCController([]RPCEndpoint{{URL: testRPCEndpoint}})
+
+	return controller
+}
+
+// batchResponder answers JSON-RPC batches by passing each request of the batch to the responder.
+func batchResponder(responder httpmock.Responder) httpmock.Responder {
+	return func(request *http.Request) (*http.Response, error) {
+		defer request.Body.Close()
+
+		requestBodyBytes, err := ioutil.ReadAll(request.Body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(requestBodyBytes) == 0 || requestBodyBytes[0] != '[' {
+			request.Body = ioutil.NopCloser(bytes.NewReader(requestBodyBytes))
+
+			return responder(request)
+		}
+
+		batch := make([]json.RawMessage, 0)
+
+		if err := json.Unmarshal(requestBodyBytes, &batch); err != nil {
+			return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+		}
+
+		responses := make([]map[string]interface{}, 0, len(batch))
+
+		for _, batchRequest := range batch {
+			singleRequest, _ := http.NewRequest(request.Method, request.URL.String(), bytes.NewReader(batchRequest))
+
+			singleResponse, err := responder(singleRequest)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if singleResponse.StatusCode != http.StatusOK {
+				return singleResponse, nil
+			}
+
+			response := make(map[string]interface{})
+
+			if err := json.NewDecoder(singleResponse.Body).Decode(&response); err != nil {
+				return nil, err
+			}
+
+			header := new(requestPayload)
+			json.Unmarshal(batchRequest, header)
+			response["id"] = header.ID
+
+			responses = append(responses, response)
+		}
+
+		return httpmock.NewJsonResponse(http.StatusOK, responses)
+	}
+}
+
+func TestMakeRPCController(t *testing.T) {
+	_, err := MakeRPCController([]RPCEndpoint{})
+
+	assert.EqualError(t, err, "no RPC endpoints configured")
+
+	_, err = MakeRPCController([]RPCEndpoint{{URL: "ftp://rpc.test/"}})
+
+	assert.EqualError(t, err, "unsupported RPC endpoint scheme 'ftp'")
+
+	controller, err := MakeRPCController([]RPCEndpoint{{URL: "http://localhost:8545"}, {URL: "wss://rpc.test/ws"}})
+
+	if assert.NoError(t, err) {
+		assert.Len(t, controller.client.transports, 2)
+	}
+
+	_, err = InfuraController{}.callInfura(makeRequestPayload("eth_chainId", []interface{}{}))
+
+	assert.EqualError(t, err, "RPC endpoints are not set")
+}
+
+func TestRPCClient_Failover(t *testing.T) {
+	controller, _ := MakeRPCController([]RPCEndpoint{
+		{URL: "https://primary.test/"},
+		{URL: "https://backup.test/", Headers: map[string]string{"Authorization": "Bearer token"}},
+	})
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	primaryCalls := 0
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		"https://primary.test/",
+		func(request *http.Request) (*http.Response, error) {
+			primaryCalls++
+
+			return httpmock.NewStringResponse(http.StatusBadGateway, "bad gateway"), nil
+		},
+	)
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		"https://backup.test/",
+		func(request *http.Request) (*http.Response, error) {
+			if request.Header.Get("Authorization") != "Bearer token" {
+				return httpmock.NewStringResponse(http.StatusUnauthorized, "unauthorized"), nil
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "0x4"}`), nil
+		},
+	)
+
+	response, err := controller.callInfura(makeRequestPayload("eth_chainId", []interface{}{}))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "0x4", response.Result)
+	}
+
+	// The endpoint which answered last is asked first.
+	_, err = controller.callInfura(makeRequestPayload("eth_chainId", []interface{}{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, primaryCalls)
+
+	// JSON-RPC errors come from a working endpoint, so they aren't failed over.
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		"https://backup.test/",
+		httpmock.NewStringResponder(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "nonce too low"}}`),
+	)
+
+	_, err = controller.callInfura(makeRequestPayload("eth_sendRawTransaction", []interface{}{"0x"}))
+
+	assert.EqualError(t, err, "nonce too low")
+	assert.Equal(t, 1, primaryCalls)
+
+	httpmock.RegisterResponder(http.MethodPost, "https://backup.test/", httpmock.NewStringResponder(http.StatusTooManyRequests, ""))
+
+	_, err = controller.callInfura(makeRequestPayload("eth_chainId", []interface{}{}))
+
+	assert.EqualError(t, err, "endpoint responded with status 502")
+	assert.Equal(t, 2, primaryCalls)
+}
+
+func TestRPCClient_Failover_Hung(t *testing.T) {
+	release := make(chan struct{})
+
+	hung := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-release
+	}))
+
+	defer hung.Close()
+	defer close(release)
+
+	// The result is decoded before the id turns out to be malformed.
+	malformed := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(`{"jsonrpc": "2.0", "result": "0x1", "id": "one"}`))
+	}))
+
+	defer malformed.Close()
+
+	backup := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(`{"jsonrpc": "2.0", "result": null}`))
+	}))
+
+	defer backup.Close()
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+
+	controller := InfuraController{client: &RPCClient{transports: []rpcTransport{
+		httpTransport{RPCEndpoint{URL: hung.URL}, client},
+		httpTransport{RPCEndpoint{URL: malformed.URL}, client},
+		httpTransport{RPCEndpoint{URL: backup.URL}, client},
+	}}}
+
+	startedAt := time.Now()
+
+	response, err := controller.callInfura(makeRequestPayload("eth_getTransactionByHash", []interface{}{"0x0"}))
+
+	// Nothing of the malformed answer is kept.
+	if assert.NoError(t, err) {
+		assert.Equal(t, "", response.Result)
+	}
+
+	assert.True(t, time.Since(startedAt) < time.Second)
+}
+
+func TestInfuraController_SendRawTransaction(t *testing.T) {
+	controller, _ := MakeRPCController([]RPCEndpoint{{URL: "https://primary.test/"}, {URL: "https://backup.test/"}})
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodPost, "https://primary.test/", httpmock.NewStringResponder(http.StatusGatewayTimeout, ""))
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		"https://backup.test/",
+		httpmock.NewStringResponder(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "already known"}}`),
+	)
+
+	// The primary endpoint may have accepted the transaction before timing out.
+	isAmbiguous, err := controller.sendRawTransaction([]byte{1, 2, 3})
+
+	assert.NoError(t, err)
+	assert.False(t, isAmbiguous)
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		"https://backup.test/",
+		httpmock.NewStringResponder(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "nonce too low"}}`),
+	)
+
+	// The backup endpoint answered last and is asked first, so its rejection is certain.
+	isAmbiguous, err = controller.sendRawTransaction([]byte{1, 2, 3})
+
+	assert.EqualError(t, err, "nonce too low")
+	assert.False(t, isAmbiguous)
+
+	httpmock.RegisterResponder(http.MethodPost, "https://backup.test/", httpmock.NewStringResponder(http.StatusGatewayTimeout, ""))
+
+	isAmbiguous, err = controller.sendRawTransaction([]byte{1, 2, 3})
+
+	assert.Error(t, err)
+	assert.True(t, isAmbiguous)
+
+	// The rejection may be caused by the transaction sent through the backup endpoint before it failed.
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		"https://primary.test/",
+		httpmock.NewStringResponder(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "nonce too low"}}`),
+	)
+
+	isAmbiguous, err = controller.sendRawTransaction([]byte{1, 2, 3})
+
+	assert.EqualError(t, err, "nonce too low")
+	assert.True(t, isAmbiguous)
+}
+
+func TestRPCClient_Websocket(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(connection *websocket.Conn) {
+		for {
+			var message json.RawMessage
+
+			if err := websocket.JSON.Receive(connection, &message); err != nil {
+				return
+			}
+
+			websocket.JSON.Send(connection, map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  "eth_subscription",
+				"params":  map[string]interface{}{"subscription": "0x1", "result": "0x2"},
+			})
+
+			batch := make([]requestPayload, 0)
+
+			if message[0] != '[' {
+				request := requestPayload{}
+				json.Unmarshal(message, &request)
+				batch = append(batch, request)
+			} else {
+				json.Unmarshal(message, &batch)
+			}
+
+			responses := make([]map[string]interface{}, 0, len(batch))
+
+			for _, request := range batch {
+				responses = append(responses, map[string]interface{}{
+					"jsonrpc": "2.0",
+					"id":      request.ID,
+					"result":  request.Method,
+				})
+			}
+
+			if message[0] != '[' {
+				websocket.JSON.Send(connection, responses[0])
+			} else {
+				websocket.JSON.Send(connection, responses)
+			}
+		}
+	}))
+	defer server.Close()
+
+	controller, err := MakeRPCController([]RPCEndpoint{{URL: strings.Replace(server.URL, "http://", "ws://", 1)}})
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for _, method := range []string{"eth_chainId", "eth_blockNumber"} {
+		response, err := controller.callInfura(makeRequestPayload(method, []interface{}{}))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, method, response.Result)
+		}
+	}
+
+	responses, err := controller.callInfuraBatch([]requestPayload{
+		makeRequestPayload("eth_chainId", []interface{}{}),
+		makeRequestPayload("eth_blockNumber", []interface{}{}),
+	})
+
+	if assert.NoError(t, err) && assert.Len(t, responses, 2) {
+		assert.Equal(t, `"eth_chainId"`, string(responses[0].Result))
+		assert.Equal(t, `"eth_blockNumber"`, string(responses[1].Result))
+	}
+}
+
+func TestRPCClient_IPC(t *testing.T) {
+	directory, err := ioutil.TempDir("", "ipc")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(directory)
+
+	socketPath := filepath.Join(directory, "clef.ipc")
+	listener, err := net.Listen("unix", socketPath)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	defer listener.Close()
+
+	go func() {
+		for {
+			connection, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			request := new(requestPayload)
+			json.NewDecoder(connection).Decode(request)
+			json.NewEncoder(connection).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": request.Method})
+			connection.Close()
+		}
+	}()
+
+	controller, err := MakeRPCController([]RPCEndpoint{{URL: socketPath}})
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	response, err := controller.callInfura(makeRequestPayload("account_version", []interface{}{}))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "account_version", response.Result)
+	}
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	SignerPoolRoundRobin   = "roundRobin"
+	SignerPoolLeastPending = "leastPending"
+)
+
+var ErrNoFundedSigner = errors.New("no signing account can pay for the transaction")
+
+var ErrSignerPoolPaused = errors.New("signer pool is paused until the signing accounts are funded")
+
+// SignerPool assigns mint transactions to the owner and the administrators of the crowdsale, so that mints
+// don't queue behind the nonces of one account. Every account has nonces of its own in the nonce manager.
+type SignerPool struct {
+	InfuraController
+	signers  []*TransactionSigner
+	strategy string
+	mutex    *sync.Mutex
+	next     int
+	// Mint transactions assigned to the accounts, which haven't been confirmed or given up on yet.
+	pending  map[common.Address]int
+	isPaused bool
+}
+
+func MakeSignerPool(infuraController InfuraController, signers []*TransactionSigner, strategy string) (*SignerPool, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("signer pool must have at least one signer")
+	}
+
+	if strategy != SignerPoolRoundRobin && strategy != SignerPoolLeastPending {
+		return nil, fmt.Errorf("unknown signer pool strategy '%s'", strategy)
+	}
+
+	accounts := make(map[common.Address]bool)
+
+	for _, signer := range signers {
+		if accounts[signer.Address()] {
+			return nil, fmt.Errorf("account %s is in the signer pool twice", signer.Address().Hex())
+		}
+
+		accounts[signer.Address()] = true
+	}
+
+	return &SignerPool{
+		InfuraController: infuraController,
+		signers:          signers,
+		strategy:         strategy,
+		mutex:            &sync.Mutex{},
+		pending:          make(map[common.Address]int),
+	}, nil
+}
+
+func (pool *SignerPool) Signers() []*TransactionSigner {
+	return pool.signers
+}
+
+// Signer returns the signer of the account, e.g. to replace a transaction it has sent.
+func (pool *SignerPool) Signer(account common.Address) (*TransactionSigner, error) {
+	for _, signer := range pool.signers {
+		if signer.Address() == account {
+			return signer, nil
+		}
+	}
+
+	return nil, fmt.Errorf("account %s is not in the signer pool", account.Hex())
+}
+
+func (pool *SignerPool) GetBalance(account common.Address) (*big.Int, error) {
+	response, err := pool.InfuraController.callInfura(makeRequestPayload(
+		"eth_getBalance",
+		[]interface{}{account, "pending"},
+	))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hexutil.DecodeBig(response.Result)
+}
+
+// candidates orders the signers by preference. Round robin starts with the one after the last assigned,
+// least pending prefers the accounts with the fewest pending transactions, keeping the round robin order on ties.
+func (pool *SignerPool) candidates() []*TransactionSigner {
+	candidates := make([]*TransactionSigner, 0, len(pool.signers))
+
+	for index := range pool.signers {
+		candidates = append(candidates, pool.signers[(pool.next+index)%len(pool.signers)])
+	}
+
+	if pool.strategy == SignerPoolLeastPending {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return pool.pending[candidates[i].Address()] < pool.pending[candidates[j].Address()]
+		})
+	}
+
+	return candidates
+}
+
+// Preferred returns the signer Acquire tries first.
+func (pool *SignerPool) Preferred() *TransactionSigner {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return pool.candidates()[0]
+}
+
+// Acquire assigns the transaction costing at most cost to the preferred signer whose account can pay for it.
+// The signer must be released once the transaction is confirmed or given up on.
+func (pool *SignerPool) Acquire(cost *big.Int) (*TransactionSigner, error) {
+	pool.mutex.Lock()
+	isPaused := pool.isPaused
+	candidates := pool.candidates()
+	pool.mutex.Unlock()
+
+	if isPaused {
+		return nil, ErrSignerPoolPaused
+	}
+
+	var lastErr error
+
+	for _, signer := range candidates {
+		balance, err := pool.GetBalance(signer.Address())
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if balance.Cmp(cost) == -1 {
+			continue
+		}
+
+		pool.mutex.Lock()
+		defer pool.mutex.Unlock()
+
+		pool.pending[signer.Address()]++
+
+		for index := range pool.signers {
+			if pool.signers[index] == signer {
+				pool.next = (index + 1) % len(pool.signers)
+			}
+		}
+
+		return signer, nil
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	return nil, ErrNoFundedSigner
+}
+
+// Release is called once the transaction assigned to the account is confirmed or given up on.
+func (pool *SignerPool) Release(account common.Address) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if pool.pending[account] > 0 {
+		pool.pending[account]--
+	}
+}
+
+// Pause makes Acquire fail with ErrSignerPoolPaused until the pool is resumed.
+func (pool *SignerPool) Pause() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	pool.isPaused = true
+}
+
+func (pool *SignerPool) Resume() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	pool.isPaused = false
+}
+
+func (pool *SignerPool) IsPaused() bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return pool.isPaused
+}
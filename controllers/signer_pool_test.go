@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func testTransactionSigners(count int) []*TransactionSigner {
+	signers := make([]*TransactionSigner, count)
+
+	for index := range signers {
+		key, _ := crypto.GenerateKey()
+
+		signers[index] = &TransactionSigner{chainID: big.NewInt(5), accountSigner: &KeySigner{key}}
+	}
+
+	return signers
+}
+
+// registerBalances makes the fake node answer eth_getBalance with the balances of the accounts.
+func registerBalances(balances map[common.Address]int64) {
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		batchResponder(func(request *http.Request) (*http.Response, error) {
+			requestBody := new(requestPayload)
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return nil, err
+			}
+
+			balance, ok := balances[common.HexToAddress(requestBody.Params[0].(string))]
+
+			if requestBody.Method != "eth_getBalance" || !ok {
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "unknown account"}}`), nil
+			}
+
+			return httpmock.NewJsonResponse(http.StatusOK, map[string]interface{}{
+				"jsonrpc": "2.0",
+				"result":  hexutil.EncodeBig(big.NewInt(balance)),
+			})
+		}),
+	)
+}
+
+func TestMakeSignerPool(t *testing.T) {
+	signers := testTransactionSigners(2)
+
+	_, err := MakeSignerPool(testInfuraController(), nil, SignerPoolRoundRobin)
+
+	assert.EqualError(t, err, "signer pool must have at least one signer")
+
+	_, err = MakeSignerPool(testInfuraController(), signers, "random")
+
+	assert.EqualError(t, err, "unknown signer pool strategy 'random'")
+
+	_, err = MakeSignerPool(testInfuraController(), []*TransactionSigner{signers[0], signers[1], signers[0]}, SignerPoolRoundRobin)
+
+	assert.EqualError(t, err, "account "+signers[0].Address().Hex()+" is in the signer pool twice")
+
+	pool, err := MakeSignerPool(testInfuraController(), signers, SignerPoolLeastPending)
+
+	if assert.NoError(t, err) {
+		signer, err := pool.Signer(signers[1].Address())
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, signers[1], signer)
+		}
+
+		_, err = pool.Signer(common.HexToAddress("0x123"))
+
+		assert.Error(t, err)
+	}
+}
+
+func TestSignerPool_Acquire_RoundRobin(t *testing.T) {
+	signers := testTransactionSigners(3)
+	pool, _ := MakeSignerPool(testInfuraController(), signers, SignerPoolRoundRobin)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerBalances(map[common.Address]int64{
+		signers[0].Address(): 1000,
+		signers[1].Address(): 10,
+		signers[2].Address(): 1000,
+	})
+
+	// The second account can't pay and is skipped.
+	for _, expected := range []*TransactionSigner{signers[0], signers[2], signers[0], signers[2]} {
+		signer, err := pool.Acquire(big.NewInt(100))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected.Address(), signer.Address())
+		}
+	}
+
+	signer, err := pool.Acquire(big.NewInt(10))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, signers[0].Address(), signer.Address())
+	}
+
+	_, err = pool.Acquire(big.NewInt(5000))
+
+	assert.Equal(t, ErrNoFundedSigner, err)
+}
+
+func TestSignerPool_Acquire_LeastPending(t *testing.T) {
+	signers := testTransactionSigners(3)
+	pool, _ := MakeSignerPool(testInfuraController(), signers, SignerPoolLeastPending)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerBalances(map[common.Address]int64{
+		signers[0].Address(): 1000,
+		signers[1].Address(): 1000,
+		signers[2].Address(): 1000,
+	})
+
+	acquired := make([]common.Address, 0)
+
+	for i := 0; i < 3; i++ {
+		signer, err := pool.Acquire(big.NewInt(100))
+
+		if assert.NoError(t, err) {
+			acquired = append(acquired, signer.Address())
+		}
+	}
+
+	assert.Equal(t, []common.Address{signers[0].Address(), signers[1].Address(), signers[2].Address()}, acquired)
+
+	// The account whose transaction got confirmed has the fewest pending ones.
+	pool.Release(signers[1].Address())
+
+	signer, err := pool.Acquire(big.NewInt(100))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, signers[1].Address(), signer.Address())
+	}
+
+	pool.Release(signers[2].Address())
+	pool.Release(signers[2].Address())
+	pool.Release(signers[2].Address())
+
+	assert.Equal(t, 0, pool.pending[signers[2].Address()])
+}
+
+func TestSignerPool_Acquire_Fail(t *testing.T) {
+	signers := testTransactionSigners(2)
+	pool, _ := MakeSignerPool(testInfuraController(), signers, SignerPoolRoundRobin)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerBalances(map[common.Address]int64{signers[1].Address(): 1000})
+
+	signer, err := pool.Acquire(big.NewInt(100))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, signers[1].Address(), signer.Address())
+	}
+
+	registerBalances(map[common.Address]int64{})
+
+	_, err = pool.Acquire(big.NewInt(100))
+
+	assert.EqualError(t, err, "unknown account")
+}
+
+func TestSignerPool_Pause(t *testing.T) {
+	signers := testTransactionSigners(1)
+	pool, _ := MakeSignerPool(testInfuraController(), signers, SignerPoolRoundRobin)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerBalances(map[common.Address]int64{signers[0].Address(): 1000})
+
+	pool.Pause()
+
+	assert.True(t, pool.IsPaused())
+
+	_, err := pool.Acquire(big.NewInt(100))
+
+	assert.Equal(t, ErrSignerPoolPaused, err)
+
+	pool.Resume()
+
+	assert.False(t, pool.IsPaused())
+
+	signer, err := pool.Acquire(big.NewInt(100))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, signers[0].Address(), signer.Address())
+	}
+}
@@ -1,18 +1,17 @@
 package controllers
 
 import (
-	"crypto/ecdsa"
 	"errors"
+	"fmt"
+	"log"
 	"math/big"
 	"strings"
+	"time"
 
 	"MCW-btc-module/gocontracts"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 type TokenManagementController struct {
@@ -20,17 +19,25 @@ type TokenManagementController struct {
 	crowdsaleContractABI     abi.ABI
 	crowdsaleContractAddress common.Address
 	crowdsaleOwnerAddress    common.Address
-	OwnerPrivateKey          *ecdsa.PrivateKey
+	transactionSigner        *TransactionSigner
+	signerPool               *SignerPool
+	feeCeiling               *big.Int
+	feeController            FeeController
+	nonceManager             *NonceManager
 }
 
-func MakeTokenManagementController(infuraController InfuraController, crowdsaleAddress string, crowdsaleOwnerAddress string, crowdsaleOwnerPrivateKey string) (*TokenManagementController, error) {
-	crowdsaleABI, err := abi.JSON(strings.NewReader(gocontracts.MocrowCoinCrowdsaleABI))
-
-	if err != nil {
-		return nil, err
-	}
+// MintTransaction is the sent mint transaction.
+type MintTransaction struct {
+	From     common.Address
+	Hash     common.Hash
+	Nonce    uint64
+	GasLimit uint64
+	Fees     TransactionFees
+	Data     []byte
+}
 
-	key, err := crypto.HexToECDSA(crowdsaleOwnerPrivateKey)
+func MakeTokenManagementController(infuraController InfuraController, crowdsaleAddress string, crowdsaleOwnerAddress string) (*TokenManagementController, error) {
+	crowdsaleABI, err := abi.JSON(strings.NewReader(gocontracts.MocrowCoinCrowdsaleABI))
 
 	if err != nil {
 		return nil, err
@@ -41,226 +48,760 @@ func MakeTokenManagementController(infuraController InfuraController, crowdsaleA
 		crowdsaleContractABI:     crowdsaleABI,
 		crowdsaleContractAddress: common.HexToAddress(crowdsaleAddress),
 		crowdsaleOwnerAddress:    common.HexToAddress(crowdsaleOwnerAddress),
-		OwnerPrivateKey:          key,
+		feeController:            FeeController{InfuraController: infuraController},
+		nonceManager:             MakeNonceManager(infuraController, nil),
 	}, nil
 }
 
-func (controller TokenManagementController) getCrowdaleParameter(parameter string) (*common.Address, error) {
-	return controller.InfuraController.retrieveParameter(parameter, controller.crowdsaleContractABI, controller.crowdsaleContractAddress)
+// WithFeeController returns the controller which prices mint transactions with the fee controller.
+func (controller TokenManagementController) WithFeeController(feeController FeeController) TokenManagementController {
+	controller.feeController = feeController
+
+	return controller
+}
+
+// WithNonceManager returns the controller which takes the nonces of mint transactions from the manager.
+func (controller TokenManagementController) WithNonceManager(nonceManager *NonceManager) TokenManagementController {
+	controller.nonceManager = nonceManager
+
+	return controller
+}
+
+// WithTransactionSigner returns the controller which signs mint transactions with the signer.
+func (controller TokenManagementController) WithTransactionSigner(transactionSigner *TransactionSigner) TokenManagementController {
+	controller.transactionSigner = transactionSigner
+
+	return controller
+}
+
+// WithSignerPool returns the controller which assigns mint transactions to the signers of the pool rather than
+// the transaction signer.
+func (controller TokenManagementController) WithSignerPool(signerPool *SignerPool) TokenManagementController {
+	controller.signerPool = signerPool
+
+	return controller
+}
+
+// WithFeeCeiling returns the controller which doesn't mint while the network asks more per gas than the ceiling,
+// unless forced to. A zero ceiling is not applied.
+func (controller TokenManagementController) WithFeeCeiling(feeCeiling *big.Int) TokenManagementController {
+	controller.feeCeiling = feeCeiling
+
+	return controller
+}
+
+const (
+	PhasePreICO = "preIco"
+	PhaseICO    = "ico"
+)
+
+var ErrBelowMinimalInvestment = errors.New("investment is below minimal investment")
+
+var ErrFeesAboveCeiling = errors.New("network fees are above the fee ceiling")
+
+func (controller TokenManagementController) getCrowdaleParameter(result interface{}, parameter string, arguments ...interface{}) error {
+	return controller.InfuraController.retrieveParameter(result, parameter, controller.crowdsaleContractABI, controller.crowdsaleContractAddress, arguments...)
 }
 
 func (controller TokenManagementController) isPreICO() (bool, error) {
-	parameter, err := controller.getCrowdaleParameter("isPreIco")
-	if err != nil {
+	var parameter bool
+
+	if err := controller.getCrowdaleParameter(&parameter, "isPreIco"); err != nil {
 		return false, err
 	}
 
-	return parameter.Big().Int64() == 1, nil
+	return parameter, nil
 }
 
 func (controller TokenManagementController) preICOTokensRemaining() (*big.Int, error) {
-	parameter, err := controller.getCrowdaleParameter("tokensRemainingPreIco")
-	if err != nil {
+	var parameter *big.Int
+
+	if err := controller.getCrowdaleParameter(&parameter, "tokensRemainingPreIco"); err != nil {
 		return nil, err
 	}
 
-	return parameter.Big(), nil
+	return parameter, nil
 }
 
 func (controller TokenManagementController) preICOExchangeRate() (*big.Int, error) {
-	parameter, err := controller.getCrowdaleParameter("TOKEN_RATE_PRE_ICO")
-	if err != nil {
+	var parameter *big.Int
+
+	if err := controller.getCrowdaleParameter(&parameter, "preIcoTokenRate"); err != nil {
 		return nil, err
 	}
 
-	return parameter.Big(), nil
+	return parameter, nil
 }
 
 func (controller TokenManagementController) isICO() (bool, error) {
-	parameter, err := controller.getCrowdaleParameter("isIco")
-	if err != nil {
+	var parameter bool
+
+	if err := controller.getCrowdaleParameter(&parameter, "isIco"); err != nil {
 		return false, err
 	}
 
-	return parameter.Big().Int64() == 1, nil
+	return parameter, nil
 }
 
 func (controller TokenManagementController) icoTokensRemaining() (*big.Int, error) {
-	parameter, err := controller.getCrowdaleParameter("tokensRemainingIco")
-	if err != nil {
+	var parameter *big.Int
+
+	if err := controller.getCrowdaleParameter(&parameter, "tokensRemainingIco"); err != nil {
 		return nil, err
 	}
 
-	return parameter.Big(), nil
+	return parameter, nil
 }
 
 func (controller TokenManagementController) icoExchangeRate() (*big.Int, error) {
-	parameter, err := controller.getCrowdaleParameter("TOKEN_RATE_ICO")
+	var parameter *big.Int
+
+	if err := controller.getCrowdaleParameter(&parameter, "icoTokenRate"); err != nil {
+		return nil, err
+	}
+
+	return parameter, nil
+}
+
+// crowdsaleState is the part of the crowdsale state purchases depend on, read in one batch.
+type crowdsaleState struct {
+	isPreICO                        bool
+	isICO                           bool
+	preICOTokenRate                 *big.Int
+	preICOTokenRateNegativeDecimals *big.Int
+	icoTokenRate                    *big.Int
+	icoTokenRateNegativeDecimals    *big.Int
+	preICOTokensRemaining           *big.Int
+	icoTokensRemaining              *big.Int
+	minimalInvestment               *big.Int
+	maximalInvestment               *big.Int
+	preICOInvestment                *big.Int
+	icoInvestment                   *big.Int
+}
+
+// getCrowdsaleState also reads the investments of the investor unless it's nil.
+func (controller TokenManagementController) getCrowdsaleState(investor *common.Address) (*crowdsaleState, error) {
+	state := &crowdsaleState{
+		preICOInvestment: big.NewInt(0),
+		icoInvestment:    big.NewInt(0),
+	}
+
+	calls := []contractCall{
+		makeContractCall(&state.isPreICO, "isPreIco"),
+		makeContractCall(&state.isICO, "isIco"),
+		makeContractCall(&state.preICOTokenRate, "preIcoTokenRate"),
+		makeContractCall(&state.preICOTokenRateNegativeDecimals, "preIcoTokenRateNegativeDecimals"),
+		makeContractCall(&state.icoTokenRate, "icoTokenRate"),
+		makeContractCall(&state.icoTokenRateNegativeDecimals, "icoTokenRateNegativeDecimals"),
+		makeContractCall(&state.preICOTokensRemaining, "tokensRemainingPreIco"),
+		makeContractCall(&state.icoTokensRemaining, "tokensRemainingIco"),
+		makeContractCall(&state.minimalInvestment, "MINIMAL_INVESTMENT"),
+		makeContractCall(&state.maximalInvestment, "MAXIMAL_INVESTMENT"),
+	}
+
+	if investor != nil {
+		calls = append(
+			calls,
+			makeContractCall(&state.preICOInvestment, "getPreIcoInvestment", *investor),
+			makeContractCall(&state.icoInvestment, "getIcoInvestment", *investor),
+		)
+	}
+
+	if err := controller.InfuraController.retrieveParameters(controller.crowdsaleContractABI, controller.crowdsaleContractAddress, calls); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (state crowdsaleState) phase() (string, error) {
+	if state.isPreICO {
+		return PhasePreICO, nil
+	}
+
+	if state.isICO {
+		return PhaseICO, nil
+	}
+
+	return "", errors.New("it's neither pre-ico, nor ico")
+}
+
+func (state crowdsaleState) investment() *big.Int {
+	if state.isPreICO {
+		return state.preICOInvestment
+	} else if state.isICO {
+		return state.icoInvestment
+	}
+
+	return big.NewInt(0)
+}
+
+func (state crowdsaleState) tokensLeft() *big.Int {
+	if state.isPreICO {
+		return state.preICOTokensRemaining
+	} else if state.isICO {
+		return state.icoTokensRemaining
+	}
+
+	return big.NewInt(0)
+}
+
+func (state crowdsaleState) tokenRate() (*TokenRate, error) {
+	phase, err := state.phase()
+
 	if err != nil {
 		return nil, err
 	}
 
-	return parameter.Big(), nil
+	rate, negativeDecimals := state.icoTokenRate, state.icoTokenRateNegativeDecimals
+
+	if phase == PhasePreICO {
+		rate, negativeDecimals = state.preICOTokenRate, state.preICOTokenRateNegativeDecimals
+	}
+
+	if rate.Sign() != 1 {
+		return nil, errors.New("token rate must be positive")
+	}
+
+	return &TokenRate{rate, negativeDecimals}, nil
+}
+
+func (controller TokenManagementController) GetInvestment(investor common.Address) (*big.Int, error) {
+	state, err := controller.getCrowdsaleState(&investor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return state.investment(), nil
+}
+
+// CheckInvestmentLimits returns the accepted and the refundable parts of weiAmount.
+func (controller TokenManagementController) CheckInvestmentLimits(investor common.Address, weiAmount *big.Int) (*big.Int, *big.Int, error) {
+	state, err := controller.getCrowdsaleState(&investor)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if weiAmount.Cmp(state.minimalInvestment) == -1 {
+		return nil, nil, ErrBelowMinimalInvestment
+	}
+
+	accepted, refunded := applyInvestmentLimits(weiAmount, state.minimalInvestment, state.maximalInvestment, state.investment())
+
+	return accepted, refunded, nil
+}
+
+func applyInvestmentLimits(weiAmount *big.Int, minimum *big.Int, maximum *big.Int, investment *big.Int) (*big.Int, *big.Int) {
+	allowed := big.NewInt(0).Sub(maximum, investment)
+
+	if allowed.Sign() == -1 {
+		allowed.SetInt64(0)
+	}
+
+	if weiAmount.Cmp(allowed) != 1 {
+		return weiAmount, big.NewInt(0)
+	}
+
+	// Whatever is left after capping has to pass the minimum on its own, otherwise the whole amount is refunded.
+	if allowed.Cmp(minimum) == -1 {
+		return big.NewInt(0), weiAmount
+	}
+
+	return allowed, big.NewInt(0).Sub(weiAmount, allowed)
+}
+
+func (controller TokenManagementController) GetPhase() (string, error) {
+	state, err := controller.getCrowdsaleState(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return state.phase()
 }
 
 func (controller TokenManagementController) GetTokensLeft() (*big.Int, error) {
-	isPreICO, err := controller.isPreICO()
+	state, err := controller.getCrowdsaleState(nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	isICO, err := controller.isICO()
+	return state.tokensLeft(), nil
+}
+
+// GetTokenRate returns the token rate of the current phase, which admins may change during the crowdsale.
+func (controller TokenManagementController) GetTokenRate() (*TokenRate, error) {
+	state, err := controller.getCrowdsaleState(nil)
+
 	if err != nil {
 		return nil, err
 	}
 
-	tokensLeft := big.NewInt(0)
+	return state.tokenRate()
+}
+
+// GetICOBonus mirrors the bonus transferTokensIco adds to tokensAmount, given the time of the block mining the purchase.
+// Bonuses apply to the ICO only.
+func (controller TokenManagementController) GetICOBonus(weiAmount *big.Int, tokensAmount *big.Int, now time.Time) (int64, *big.Int, error) {
+	names := []string{
+		"compaignAllocationAndBonusRemainingTokens",
+		"icoTenPercentBonusEnded",
+		"icoFivePercentBonusEnded",
+		"MINIMAL_TEN_PERCENT_BONUS_BY_VALUE",
+		"MINIMAL_FIVE_PERCENT_BONUS_BY_VALUE",
+	}
+
+	values := make([]*big.Int, len(names))
+	calls := make([]contractCall, len(names))
+
+	for index, name := range names {
+		calls[index] = makeContractCall(&values[index], name)
+	}
+
+	if err := controller.InfuraController.retrieveParameters(controller.crowdsaleContractABI, controller.crowdsaleContractAddress, calls); err != nil {
+		return 0, nil, err
+	}
+
+	parameters := make(map[string]*big.Int)
+
+	for index, name := range names {
+		parameters[name] = values[index]
+	}
+
+	bonusTokensRemaining := parameters["compaignAllocationAndBonusRemainingTokens"]
+
+	if bonusTokensRemaining.Sign() == 0 {
+		return 0, big.NewInt(0), nil
+	}
+
+	bonus := int64(0)
+	timestamp := big.NewInt(now.Unix())
+
+	if timestamp.Cmp(parameters["icoTenPercentBonusEnded"]) == -1 {
+		bonus += 10
+	} else if timestamp.Cmp(parameters["icoFivePercentBonusEnded"]) == -1 {
+		bonus += 5
+	}
+
+	if weiAmount.Cmp(parameters["MINIMAL_TEN_PERCENT_BONUS_BY_VALUE"]) != -1 {
+		bonus += 10
+	} else if weiAmount.Cmp(parameters["MINIMAL_FIVE_PERCENT_BONUS_BY_VALUE"]) != -1 {
+		bonus += 5
+	}
+
+	bonusTokens := big.NewInt(0).Mul(tokensAmount, big.NewInt(bonus))
+	bonusTokens.Div(bonusTokens, big.NewInt(100))
+
+	if bonusTokens.Cmp(bonusTokensRemaining) == 1 {
+		bonusTokens = bonusTokensRemaining
+	}
+
+	return bonus, bonusTokens, nil
+}
+
+// CapByTokensLeft reduces weiAmount so that sellTokensForBTCPreIco/sellTokensForBTCIco don't run out of tokens.
+// It returns the accepted wei and the tokens the crowdsale sells for it.
+func (controller TokenManagementController) CapByTokensLeft(weiAmount *big.Int, tokenRate *TokenRate) (*big.Int, *big.Int, error) {
+	state, err := controller.getCrowdsaleState(nil)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	acceptedWei, tokensAmount := state.capByTokensLeft(weiAmount, tokenRate)
 
-	if isPreICO {
-		tokensLeft, err = controller.preICOTokensRemaining()
-	} else if isICO {
-		tokensLeft, err = controller.icoTokensRemaining()
+	return acceptedWei, tokensAmount, nil
+}
+
+func (state crowdsaleState) capByTokensLeft(weiAmount *big.Int, tokenRate *TokenRate) (*big.Int, *big.Int) {
+	tokensAmount := tokenRate.Tokens(weiAmount)
+
+	// The crowdsale requires more tokens remaining than sold.
+	if tokensAmount.Cmp(state.tokensLeft()) == -1 {
+		return weiAmount, tokensAmount
 	}
 
-	return tokensLeft, err
+	acceptedWei := tokenRate.MaxWeiBelow(state.tokensLeft())
+
+	if acceptedWei.Cmp(state.minimalInvestment) == -1 {
+		return big.NewInt(0), big.NewInt(0)
+	}
+
+	return acceptedWei, tokenRate.Tokens(acceptedWei)
+}
+
+// MintTokens fails with ErrFeesAboveCeiling while the network fees are above the fee ceiling.
+func (controller TokenManagementController) MintTokens(receiver common.Address, weiAmount *big.Int) (*MintTransaction, error) {
+	return controller.mintTokens(receiver, weiAmount, false)
+}
+
+// ForceMintTokens mints whatever the network fees are.
+func (controller TokenManagementController) ForceMintTokens(receiver common.Address, weiAmount *big.Int) (*MintTransaction, error) {
+	return controller.mintTokens(receiver, weiAmount, true)
+}
+
+func (controller TokenManagementController) isAboveFeeCeiling(fees *TransactionFees) bool {
+	return controller.feeCeiling != nil && controller.feeCeiling.Sign() == 1 &&
+		fees.NetworkFeePerGas != nil && fees.NetworkFeePerGas.Cmp(controller.feeCeiling) == 1
 }
 
-func (controller TokenManagementController) GetTokenExchangeRate() (*big.Float, error) {
-	isPreICO, err := controller.isPreICO()
+func (controller TokenManagementController) mintTokens(receiver common.Address, weiAmount *big.Int, isForced bool) (*MintTransaction, error) {
+	if controller.signerPool != nil && controller.signerPool.IsPaused() {
+		return nil, ErrSignerPoolPaused
+	}
+
+	state, err := controller.getCrowdsaleState(nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	isICO, err := controller.isICO()
+	phase, err := state.phase()
+
 	if err != nil {
 		return nil, err
 	}
 
-	rate := big.NewInt(0)
+	method := "sellTokensForBTCIco"
 
-	if isPreICO {
-		rate, err = controller.preICOExchangeRate()
-	} else if isICO {
-		rate, err = controller.icoExchangeRate()
+	if phase == PhasePreICO {
+		method = "sellTokensForBTCPreIco"
 	}
 
-	return big.NewFloat(0).SetInt(rate), err
-}
+	packedData, err := controller.crowdsaleContractABI.Pack(method, receiver, weiAmount)
 
-func (controller TokenManagementController) MintTokens(receiver common.Address, weiAmount *big.Int) error {
-	packedData := make([]byte, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if controller.transactionSigner == nil && controller.signerPool == nil {
+		return nil, errors.New("transaction signer is not set")
+	}
 
-	isPreICO, err := controller.isPreICO()
+	fees, err := controller.feeController.GetFees()
 
 	if err != nil {
-		return err
+		return nil, errors.New("unable to fetch gas price. aborting")
 	}
 
-	isICO, err := controller.isICO()
+	if !isForced && controller.isAboveFeeCeiling(fees) {
+		return nil, ErrFeesAboveCeiling
+	}
+
+	transactionSigner, gasLimit, err := controller.acquireMintSigner(fees, phase, receiver, weiAmount, packedData)
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if isPreICO {
-		packedData, err = controller.crowdsaleContractABI.Pack("sellTokensForBTCPreIco", receiver, weiAmount)
-	} else if isICO {
-		packedData, err = controller.crowdsaleContractABI.Pack("sellTokensForBTCIco", receiver, weiAmount)
-	} else {
-		err = errors.New("it's neither pre-ico, nor ico")
+	account := transactionSigner.Address()
+
+	if err := controller.simulateMint(account, phase, receiver, weiAmount, gasLimit, packedData); err != nil {
+		controller.ReleaseMintSigner(account)
+
+		return nil, err
 	}
 
+	nonce, err := controller.nonceManager.NextNonce(account)
+
 	if err != nil {
-		return err
+		controller.ReleaseMintSigner(account)
+
+		return nil, err
 	}
 
-	gasPriceChan := make(chan common.Address)
-	gasLimitChan := make(chan common.Address)
-	nonceChan := make(chan common.Address)
+	rawTransaction, hash, err := transactionSigner.SignRawTransaction(
+		nonce,
+		controller.crowdsaleContractAddress,
+		gasLimit,
+		fees,
+		packedData,
+	)
 
-	go func() {
-		response, err := controller.InfuraController.callInfura(makeRequestPayload(
-			"eth_gasPrice",
-			[]interface{}{},
-		))
+	if err != nil {
+		controller.nonceManager.Release(account, nonce)
+		controller.ReleaseMintSigner(account)
 
-		if err != nil {
-			gasPriceChan <- common.Address{}
-			return
-		}
+		return nil, err
+	}
 
-		gasPriceChan <- common.HexToAddress(response.Result)
-	}()
+	isAmbiguous, err := controller.InfuraController.sendRawTransaction(rawTransaction)
 
-	go func() {
-		response, err := controller.InfuraController.callInfura(makeEstimateGasRequestPayload(
-			controller.crowdsaleContractAddress,
-			packedData,
-		))
+	if err != nil && !isAmbiguous {
+		controller.nonceManager.Release(account, nonce)
+		controller.ReleaseMintSigner(account)
 
-		if err != nil {
-			gasLimitChan <- common.BigToAddress(big.NewInt(500000))
-			return
+		if isNonceError(err) {
+			controller.nonceManager.Resync(account)
 		}
 
-		gasLimitChan <- common.HexToAddress(response.Result)
-	}()
+		return nil, err
+	}
+
+	// The mint which may have been sent is tracked like a sent one. Its nonce stays reserved until it's mined or dropped.
+	if err != nil {
+		log.Println("MINT TRANSACTION MAY NOT HAVE BEEN SENT", hash.Hex(), err)
+	} else {
+		controller.nonceManager.MarkSent(account, nonce)
+	}
 
-	go func() {
-		response, err := controller.InfuraController.callInfura(makeRequestPayload(
-			"eth_getTransactionCount",
-			[]interface{}{controller.crowdsaleOwnerAddress, "pending"},
-		))
+	return &MintTransaction{
+		From:     account,
+		Hash:     hash,
+		Nonce:    nonce,
+		GasLimit: gasLimit,
+		Fees:     *fees,
+		Data:     packedData,
+	}, nil
+}
 
-		if err != nil {
-			nonceChan <- common.Address{}
-			return
+// acquireMintSigner picks the signer of the mint and estimates the gas of the mint from its account. The pool picks
+// an account which can pay for the gas estimated from its preferred one, and the gas is estimated again if it picks another.
+func (controller TokenManagementController) acquireMintSigner(
+	fees *TransactionFees,
+	phase string,
+	receiver common.Address,
+	weiAmount *big.Int,
+	data []byte,
+) (*TransactionSigner, uint64, error) {
+	if controller.signerPool == nil {
+		gasLimit, err := controller.estimateMintGas(controller.transactionSigner.Address(), phase, receiver, weiAmount, data)
+
+		return controller.transactionSigner, gasLimit, err
+	}
+
+	preferred := controller.signerPool.Preferred()
+
+	gasLimit, err := controller.estimateMintGas(preferred.Address(), phase, receiver, weiAmount, data)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	transactionSigner, err := controller.signerPool.Acquire(fees.MaxCost(gasLimit))
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if transactionSigner != preferred {
+		if gasLimit, err = controller.estimateMintGas(transactionSigner.Address(), phase, receiver, weiAmount, data); err != nil {
+			controller.ReleaseMintSigner(transactionSigner.Address())
+
+			return nil, 0, err
 		}
+	}
+
+	return transactionSigner, gasLimit, nil
+}
+
+// estimateMintGas estimates the gas of the mint call from the account, explaining reverts like simulateMint.
+func (controller TokenManagementController) estimateMintGas(
+	account common.Address,
+	phase string,
+	receiver common.Address,
+	weiAmount *big.Int,
+	data []byte,
+) (uint64, error) {
+	gasLimit, err := controller.InfuraController.estimateGas(account, controller.crowdsaleContractAddress, data)
+
+	return gasLimit, controller.explainMintError(err, account, phase, receiver, weiAmount)
+}
+
+// simulateMint runs the mint call from the account before anything is signed.
+func (controller TokenManagementController) simulateMint(
+	account common.Address,
+	phase string,
+	receiver common.Address,
+	weiAmount *big.Int,
+	gasLimit uint64,
+	data []byte,
+) error {
+	err := controller.InfuraController.simulateCall(account, controller.crowdsaleContractAddress, gasLimit, data)
+
+	return controller.explainMintError(err, account, phase, receiver, weiAmount)
+}
+
+// explainMintError fills in the reason of the mint revert. The crowdsale reverts without reasons, so they are inferred from its state.
+func (controller TokenManagementController) explainMintError(
+	err error,
+	account common.Address,
+	phase string,
+	receiver common.Address,
+	weiAmount *big.Int,
+) error {
+	if revertError, ok := err.(*RevertError); ok && revertError.Reason == "" {
+		revertError.Reason = controller.explainMintRevert(account, phase, receiver, weiAmount)
+	}
+
+	return err
+}
+
+// explainMintRevert checks the requirements of sellTokensForBTCPreIco and sellTokensForBTCIco in their order.
+func (controller TokenManagementController) explainMintRevert(account common.Address, phase string, receiver common.Address, weiAmount *big.Int) string {
+	var owner common.Address
+	var isAdministrator, isWhitelisted, isPaused bool
+
+	err := controller.InfuraController.retrieveParameters(controller.crowdsaleContractABI, controller.crowdsaleContractAddress, []contractCall{
+		makeContractCall(&owner, "owner"),
+		makeContractCall(&isAdministrator, "isAdministrator", account),
+		makeContractCall(&isWhitelisted, "isWhitelisted", receiver),
+		makeContractCall(&isPaused, "paused"),
+	})
+
+	if err != nil {
+		return ""
+	}
+
+	state, err := controller.getCrowdsaleState(nil)
+
+	if err != nil {
+		return ""
+	}
+
+	if owner != account && !isAdministrator {
+		return "signing account is neither the owner nor an administrator"
+	}
 
-		nonceChan <- common.HexToAddress(response.Result)
-	}()
+	currentPhase, err := state.phase()
 
-	gasPrice := <-gasPriceChan
-	gasLimit := <-gasLimitChan
-	nonce := <-nonceChan
+	if err != nil {
+		return err.Error()
+	}
+
+	if currentPhase != phase {
+		return fmt.Sprintf("crowdsale has left the %s phase", phase)
+	}
+
+	if !isWhitelisted {
+		return "wallet is not whitelisted"
+	}
+
+	if isPaused {
+		return "crowdsale is paused"
+	}
 
-	// We do not check nonce here since it actually can be zero.
-	if (gasPrice == common.Address{}) {
-		return errors.New("unable to fetch gas price. aborting")
+	if weiAmount.Cmp(state.minimalInvestment) == -1 {
+		return ErrBelowMinimalInvestment.Error()
 	}
 
-	transaction := types.NewTransaction(
-		nonce.Big().Uint64(),
+	if tokenRate, err := state.tokenRate(); err == nil && tokenRate.Tokens(weiAmount).Cmp(state.tokensLeft()) != -1 {
+		return "not enough tokens remaining"
+	}
+
+	return ""
+}
+
+// ReplaceMintTransaction sends the transaction with the nonce and the call of the previous one, priced with the fees.
+func (controller TokenManagementController) ReplaceMintTransaction(previous MintTransaction, fees *TransactionFees) (*MintTransaction, error) {
+	transactionSigner, err := controller.getMintSigner(previous.From)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rawTransaction, hash, err := transactionSigner.SignRawTransaction(
+		previous.Nonce,
 		controller.crowdsaleContractAddress,
-		big.NewInt(0),
-		gasLimit.Big().Uint64(),
-		gasPrice.Big(),
-		packedData,
+		previous.GasLimit,
+		fees,
+		previous.Data,
 	)
 
-	signer := types.HomesteadSigner{}
+	if err != nil {
+		return nil, err
+	}
 
-	signature, err := crypto.Sign(signer.Hash(transaction).Bytes(), controller.OwnerPrivateKey)
+	isAmbiguous, err := controller.InfuraController.sendRawTransaction(rawTransaction)
+
+	if err != nil && !isAmbiguous {
+		return nil, err
+	}
 
 	if err != nil {
-		return err
+		log.Println("REPLACEMENT TRANSACTION MAY NOT HAVE BEEN SENT", hash.Hex(), err)
 	}
 
-	signedTransaction, err := transaction.WithSignature(signer, signature)
+	return &MintTransaction{
+		From:     transactionSigner.Address(),
+		Hash:     hash,
+		Nonce:    previous.Nonce,
+		GasLimit: previous.GasLimit,
+		Fees:     *fees,
+		Data:     previous.Data,
+	}, nil
+}
+
+// getMintSigner returns the signer of the account which sent a mint transaction. Transactions sent before
+// the account was recorded are signed by the transaction signer.
+func (controller TokenManagementController) getMintSigner(account common.Address) (*TransactionSigner, error) {
+	if controller.signerPool != nil && account != (common.Address{}) {
+		return controller.signerPool.Signer(account)
+	}
+
+	if controller.transactionSigner == nil {
+		return nil, errors.New("transaction signer is not set")
+	}
+
+	return controller.transactionSigner, nil
+}
+
+// mintAccount returns the account which sent a mint transaction, see getMintSigner.
+func (controller TokenManagementController) mintAccount(from common.Address) (common.Address, error) {
+	if from != (common.Address{}) {
+		return from, nil
+	}
+
+	transactionSigner, err := controller.getMintSigner(from)
 
 	if err != nil {
-		return err
+		return common.Address{}, err
+	}
+
+	return transactionSigner.Address(), nil
+}
+
+// SettleMintNonce is called once the mint transaction sent from the account with the nonce is mined or dropped,
+// so that the nonce of a mint which may not have been sent is either kept or handed out again.
+func (controller TokenManagementController) SettleMintNonce(from common.Address, nonce uint64, isMined bool) {
+	account, err := controller.mintAccount(from)
+
+	if err != nil || controller.nonceManager == nil {
+		return
+	}
+
+	if isMined {
+		controller.nonceManager.MarkSent(account, nonce)
+		return
+	}
+
+	controller.nonceManager.Release(account, nonce)
+	controller.nonceManager.Resync(account)
+}
+
+// ReleaseMintSigner is called once the mint transaction sent from the account is confirmed or given up on.
+func (controller TokenManagementController) ReleaseMintSigner(account common.Address) {
+	if controller.signerPool != nil {
+		controller.signerPool.Release(account)
 	}
+}
 
-	_, err = controller.InfuraController.callInfura(makeRequestPayload(
-		"eth_sendRawTransaction",
-		[]interface{}{hexutil.Bytes(types.Transactions{signedTransaction}.GetRlp(0)).String()},
-	))
+// IsAdministrator tells whether the crowdsale lets the account sell tokens, i.e. it's the owner or an administrator.
+func (controller TokenManagementController) IsAdministrator(account common.Address) (bool, error) {
+	var owner common.Address
+	var isAdministrator bool
+
+	err := controller.InfuraController.retrieveParameters(controller.crowdsaleContractABI, controller.crowdsaleContractAddress, []contractCall{
+		makeContractCall(&owner, "owner"),
+		makeContractCall(&isAdministrator, "isAdministrator", account),
+	})
 
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return owner == account || isAdministrator, nil
 }
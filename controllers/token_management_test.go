@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -15,45 +16,60 @@ import (
 )
 
 func TestMakeTokenManagementController(t *testing.T) {
-	controller, err := MakeTokenManagementController(InfuraController{"endpoint"}, "", "", "9df9993fcb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
 
 	if assert.NoError(t, err) {
-		assert.Equal(t, "endpoint", controller.infuraEndpoint)
+		assert.Equal(t, testInfuraController(), controller.InfuraController)
 	}
 }
 
-func TestMakeTokenManagementControllerError(t *testing.T) {
-	controller, err := MakeTokenManagementController(InfuraController{"endpoint"}, "", "", "9df9cb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
-
-	assert.Error(t, err)
-	assert.Nil(t, controller)
-}
-
 func TestTokenManagementController(t *testing.T) {
-	controller, err := MakeTokenManagementController(InfuraController{"endpoint"}, "", "", "9df9993fcb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
 
 	if assert.NoError(t, err) {
 		httpmock.Activate()
 		defer httpmock.DeactivateAndReset()
 
+		// uint256 values wider than an address must not be cut.
+		hugeValue := big.NewInt(0).Lsh(big.NewInt(0xabc), 200)
+
+		isPreICOData, _ := controller.crowdsaleContractABI.Pack("isPreIco")
+		isICOData, _ := controller.crowdsaleContractABI.Pack("isIco")
+
 		httpmock.RegisterResponder(
 			http.MethodPost,
-			controller.infuraEndpoint,
-			func(request *http.Request) (*http.Response, error) {
+			testRPCEndpoint,
+			batchResponder(func(request *http.Request) (*http.Response, error) {
+				requestBody := new(requestPayload)
+
+				if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+					return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+				}
+
+				data := requestBody.Params[0].(map[string]interface{})["data"].(string)
+				result := common.BigToHash(hugeValue)
+
+				if data == hexutil.Bytes(isPreICOData).String() || data == hexutil.Bytes(isICOData).String() {
+					result = common.Hash{}
+				}
+
 				return httpmock.NewStringResponse(
 					http.StatusOK,
-					`{"jsonrpc": "2.0", "result": "0xabc"}`,
+					`{"jsonrpc": "2.0", "result": "`+result.Hex()+`"}`,
 				), nil
-
-			},
+			}),
 		)
 
-		address, err := controller.getCrowdaleParameter("tokensRemainingIco")
+		var tokensRemaining *big.Int
 
-		if assert.NoError(t, err) {
-			assert.Equal(t, common.HexToAddress("0xabc"), *address)
+		if assert.NoError(t, controller.getCrowdaleParameter(&tokensRemaining, "tokensRemainingIco")) {
+			assert.Equal(t, hugeValue, tokensRemaining)
 		}
 
+		var isTokensRemaining bool
+
+		assert.Error(t, controller.getCrowdaleParameter(&isTokensRemaining, "tokensRemainingIco"))
+
 		boolValue, err := controller.isPreICO()
 
 		if assert.NoError(t, err) {
@@ -69,25 +85,25 @@ func TestTokenManagementController(t *testing.T) {
 		bigValue, err := controller.preICOExchangeRate()
 
 		if assert.NoError(t, err) {
-			assert.Equal(t, common.HexToAddress("0xabc").Big(), bigValue)
+			assert.Equal(t, hugeValue, bigValue)
 		}
 
 		bigValue, err = controller.preICOTokensRemaining()
 
 		if assert.NoError(t, err) {
-			assert.Equal(t, common.HexToAddress("0xabc").Big(), bigValue)
+			assert.Equal(t, hugeValue, bigValue)
 		}
 
 		bigValue, err = controller.icoExchangeRate()
 
 		if assert.NoError(t, err) {
-			assert.Equal(t, common.HexToAddress("0xabc").Big(), bigValue)
+			assert.Equal(t, hugeValue, bigValue)
 		}
 
 		bigValue, err = controller.icoTokensRemaining()
 
 		if assert.NoError(t, err) {
-			assert.Equal(t, common.HexToAddress("0xabc").Big(), bigValue)
+			assert.Equal(t, hugeValue, bigValue)
 		}
 
 		bigValue, err = controller.GetTokensLeft()
@@ -96,18 +112,20 @@ func TestTokenManagementController(t *testing.T) {
 			assert.Equal(t, big.NewInt(0), bigValue)
 		}
 
-		bigFloatValue, err := controller.GetTokenExchangeRate()
+		tokenRate, err := controller.GetTokenRate()
 
-		if assert.NoError(t, err) {
-			assert.Equal(t, big.NewFloat(0), bigFloatValue)
-		}
+		assert.EqualError(t, err, "it's neither pre-ico, nor ico")
+		assert.Nil(t, tokenRate)
 
-		assert.Equal(t, errors.New("it's neither pre-ico, nor ico"), controller.MintTokens(common.Address{}, big.NewInt(0)))
+		mintTransaction, err := controller.MintTokens(common.Address{}, big.NewInt(0))
+
+		assert.Equal(t, errors.New("it's neither pre-ico, nor ico"), err)
+		assert.Nil(t, mintTransaction)
 	}
 }
 
 func TestTokenManagementController_Fail(t *testing.T) {
-	controller, err := MakeTokenManagementController(InfuraController{"endpoint"}, "", "", "9df9993fcb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
 
 	if assert.NoError(t, err) {
 		httpmock.Activate()
@@ -115,18 +133,18 @@ func TestTokenManagementController_Fail(t *testing.T) {
 
 		httpmock.RegisterResponder(
 			http.MethodPost,
-			controller.infuraEndpoint,
-			func(request *http.Request) (*http.Response, error) {
+			testRPCEndpoint,
+			batchResponder(func(request *http.Request) (*http.Response, error) {
 
 				return nil, errors.New("mock infura failure")
 
-			},
+			}),
 		)
 
-		address, err := controller.getCrowdaleParameter("tokensRemainingIco")
+		var tokensRemaining *big.Int
 
-		assert.Error(t, err)
-		assert.Nil(t, address)
+		assert.Error(t, controller.getCrowdaleParameter(&tokensRemaining, "tokensRemainingIco"))
+		assert.Nil(t, tokensRemaining)
 
 		boolValue, err := controller.isPreICO()
 
@@ -163,17 +181,19 @@ func TestTokenManagementController_Fail(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, bigValue)
 
-		bigFloatValue, err := controller.GetTokenExchangeRate()
+		tokenRate, err := controller.GetTokenRate()
 
 		assert.Error(t, err)
-		assert.Nil(t, bigFloatValue)
+		assert.Nil(t, tokenRate)
 
-		assert.Error(t, controller.MintTokens(common.Address{}, big.NewInt(0)))
+		_, err = controller.MintTokens(common.Address{}, big.NewInt(0))
+
+		assert.Error(t, err)
 	}
 }
 
 func TestControllerPreICOStage(t *testing.T) {
-	controller, err := MakeTokenManagementController(InfuraController{"endpoint"}, "", "", "9df9993fcb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
 
 	if assert.NoError(t, err) {
 		httpmock.Activate()
@@ -181,8 +201,8 @@ func TestControllerPreICOStage(t *testing.T) {
 
 		httpmock.RegisterResponder(
 			http.MethodPost,
-			controller.infuraEndpoint,
-			func(request *http.Request) (*http.Response, error) {
+			testRPCEndpoint,
+			batchResponder(func(request *http.Request) (*http.Response, error) {
 
 				defer request.Body.Close()
 
@@ -216,7 +236,7 @@ func TestControllerPreICOStage(t *testing.T) {
 					`{"jsonrpc": "2.0", "result": "0xabc"}`,
 				), nil
 
-			},
+			}),
 		)
 
 		boolValue, err := controller.isPreICO()
@@ -228,20 +248,21 @@ func TestControllerPreICOStage(t *testing.T) {
 		bigValue, err := controller.GetTokensLeft()
 
 		if assert.NoError(t, err) {
-			assert.Equal(t, common.HexToAddress("0xabc").Big(), bigValue)
+			assert.Equal(t, new(big.Int).SetBytes(common.HexToAddress("0xabc").Bytes()), bigValue)
 		}
 
-		bigFloatValue, err := controller.GetTokenExchangeRate()
+		tokenRate, err := controller.GetTokenRate()
 
 		if assert.NoError(t, err) {
-			assert.Equal(t, big.NewFloat(0).SetInt(common.HexToAddress("0xabc").Big()), bigFloatValue)
+			assert.Equal(t, new(big.Int).SetBytes(common.HexToAddress("0xabc").Bytes()), tokenRate.Rate)
+			assert.Equal(t, new(big.Int).SetBytes(common.HexToAddress("0xabc").Bytes()), tokenRate.NegativeDecimals)
 		}
 	}
 
 }
 
 func TestControllerICOStage(t *testing.T) {
-	controller, err := MakeTokenManagementController(InfuraController{"endpoint"}, "", "", "9df9993fcb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
 
 	if assert.NoError(t, err) {
 		httpmock.Activate()
@@ -249,8 +270,8 @@ func TestControllerICOStage(t *testing.T) {
 
 		httpmock.RegisterResponder(
 			http.MethodPost,
-			controller.infuraEndpoint,
-			func(request *http.Request) (*http.Response, error) {
+			testRPCEndpoint,
+			batchResponder(func(request *http.Request) (*http.Response, error) {
 
 				defer request.Body.Close()
 
@@ -284,7 +305,7 @@ func TestControllerICOStage(t *testing.T) {
 					`{"jsonrpc": "2.0", "result": "0xabc"}`,
 				), nil
 
-			},
+			}),
 		)
 
 		boolValue, err := controller.isICO()
@@ -296,20 +317,21 @@ func TestControllerICOStage(t *testing.T) {
 		bigValue, err := controller.GetTokensLeft()
 
 		if assert.NoError(t, err) {
-			assert.Equal(t, common.HexToAddress("0xabc").Big(), bigValue)
+			assert.Equal(t, new(big.Int).SetBytes(common.HexToAddress("0xabc").Bytes()), bigValue)
 		}
 
-		bigFloatValue, err := controller.GetTokenExchangeRate()
+		tokenRate, err := controller.GetTokenRate()
 
 		if assert.NoError(t, err) {
-			assert.Equal(t, big.NewFloat(0).SetInt(common.HexToAddress("0xabc").Big()), bigFloatValue)
+			assert.Equal(t, new(big.Int).SetBytes(common.HexToAddress("0xabc").Bytes()), tokenRate.Rate)
+			assert.Equal(t, new(big.Int).SetBytes(common.HexToAddress("0xabc").Bytes()), tokenRate.NegativeDecimals)
 		}
 	}
 
 }
 
 func TestControllerICOStage_Fail(t *testing.T) {
-	controller, err := MakeTokenManagementController(InfuraController{"endpoint"}, "", "", "9df9993fcb4d9f4520770ce69f1623bccf3690489205c11e42a78bddc6526123")
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
 
 	if assert.NoError(t, err) {
 		httpmock.Activate()
@@ -317,8 +339,8 @@ func TestControllerICOStage_Fail(t *testing.T) {
 
 		httpmock.RegisterResponder(
 			http.MethodPost,
-			controller.infuraEndpoint,
-			func(request *http.Request) (*http.Response, error) {
+			testRPCEndpoint,
+			batchResponder(func(request *http.Request) (*http.Response, error) {
 
 				defer request.Body.Close()
 
@@ -349,7 +371,7 @@ func TestControllerICOStage_Fail(t *testing.T) {
 					`{"jsonrpc": "2.0", "result": "0xabc"}`,
 				), nil
 
-			},
+			}),
 		)
 
 		boolValue, err := controller.isICO()
@@ -362,10 +384,516 @@ func TestControllerICOStage_Fail(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, bigValue)
 
-		bigFloatValue, err := controller.GetTokenExchangeRate()
+		tokenRate, err := controller.GetTokenRate()
 
 		assert.Error(t, err)
-		assert.Nil(t, bigFloatValue)
+		assert.Nil(t, tokenRate)
+	}
+
+}
+
+func TestTokenManagementController_CheckInvestmentLimits(t *testing.T) {
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
+
+	if assert.NoError(t, err) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		ether := big.NewInt(1000000000000000000)
+		investor := common.HexToAddress("0x123")
+		investment := big.NewInt(0).Mul(big.NewInt(4), ether)
+
+		results := map[string]*big.Int{}
+
+		pack := func(name string, arguments ...interface{}) string {
+			data, err := controller.crowdsaleContractABI.Pack(name, arguments...)
+			assert.NoError(t, err)
+			return hexutil.Bytes(data).String()
+		}
+
+		results[pack("isPreIco")] = big.NewInt(0)
+		results[pack("isIco")] = big.NewInt(1)
+		results[pack("MINIMAL_INVESTMENT")] = big.NewInt(0).Div(ether, big.NewInt(10))
+		results[pack("MAXIMAL_INVESTMENT")] = big.NewInt(0).Mul(big.NewInt(5), ether)
+		results[pack("getIcoInvestment", investor)] = investment
+
+		// The rest of the crowdsale state is read in the same batch.
+		for _, name := range []string{"preIcoTokenRate", "preIcoTokenRateNegativeDecimals", "icoTokenRate", "icoTokenRateNegativeDecimals", "tokensRemainingPreIco", "tokensRemainingIco"} {
+			results[pack(name)] = big.NewInt(0)
+		}
+
+		results[pack("getPreIcoInvestment", investor)] = big.NewInt(0)
+
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			testRPCEndpoint,
+			batchResponder(func(request *http.Request) (*http.Response, error) {
+				defer request.Body.Close()
+
+				requestBodyBytes, err := ioutil.ReadAll(request.Body)
+
+				if err != nil {
+					return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+				}
+
+				requestBody := new(requestPayload)
+
+				if err := json.Unmarshal(requestBodyBytes, requestBody); err != nil {
+					return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+				}
+
+				data := requestBody.Params[0].(map[string]interface{})["data"].(string)
+
+				result, ok := results[data]
+
+				if !ok {
+					return nil, errors.New("unexpected call")
+				}
+
+				return httpmock.NewStringResponse(
+					http.StatusOK,
+					`{"jsonrpc": "2.0", "result": "`+common.BigToHash(result).Hex()+`"}`,
+				), nil
+			}),
+		)
+
+		accepted, refunded, err := controller.CheckInvestmentLimits(investor, big.NewInt(0).Div(ether, big.NewInt(100)))
+
+		assert.Equal(t, ErrBelowMinimalInvestment, err)
+		assert.Nil(t, accepted)
+		assert.Nil(t, refunded)
+
+		accepted, refunded, err = controller.CheckInvestmentLimits(investor, ether)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, ether, accepted)
+			assert.Equal(t, big.NewInt(0), refunded)
+		}
+
+		accepted, refunded, err = controller.CheckInvestmentLimits(investor, big.NewInt(0).Mul(big.NewInt(3), ether))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, ether, accepted)
+			assert.Equal(t, big.NewInt(0).Mul(big.NewInt(2), ether), refunded)
+		}
+
+		investment.Add(investment, big.NewInt(950000000000000000))
+
+		accepted, refunded, err = controller.CheckInvestmentLimits(investor, ether)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, big.NewInt(0), accepted)
+			assert.Equal(t, ether, refunded)
+		}
 	}
+}
+
+func TestTokenManagementController_CapByTokensLeft(t *testing.T) {
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
+
+	if assert.NoError(t, err) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		ether := big.NewInt(1000000000000000000)
+		tokensLeft, _ := new(big.Int).SetString("2788000446080000000000", 10)
+
+		results := map[string]*big.Int{}
+
+		pack := func(name string, arguments ...interface{}) string {
+			data, err := controller.crowdsaleContractABI.Pack(name, arguments...)
+			assert.NoError(t, err)
+			return hexutil.Bytes(data).String()
+		}
+
+		results[pack("isPreIco")] = big.NewInt(0)
+		results[pack("isIco")] = big.NewInt(1)
+		results[pack("icoTokenRate")] = big.NewInt(35868)
+		results[pack("icoTokenRateNegativeDecimals")] = big.NewInt(8)
+		results[pack("tokensRemainingIco")] = tokensLeft
+		results[pack("MINIMAL_INVESTMENT")] = big.NewInt(0).Div(ether, big.NewInt(10))
+
+		// The rest of the crowdsale state is read in the same batch.
+		for _, name := range []string{"preIcoTokenRate", "preIcoTokenRateNegativeDecimals", "tokensRemainingPreIco", "MAXIMAL_INVESTMENT"} {
+			results[pack(name)] = big.NewInt(0)
+		}
+
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			testRPCEndpoint,
+			batchResponder(func(request *http.Request) (*http.Response, error) {
+				defer request.Body.Close()
+
+				requestBodyBytes, err := ioutil.ReadAll(request.Body)
+
+				if err != nil {
+					return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+				}
+
+				requestBody := new(requestPayload)
+
+				if err := json.Unmarshal(requestBodyBytes, requestBody); err != nil {
+					return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+				}
+
+				data := requestBody.Params[0].(map[string]interface{})["data"].(string)
+
+				result, ok := results[data]
+
+				if !ok {
+					return nil, errors.New("unexpected call")
+				}
+
+				return httpmock.NewStringResponse(
+					http.StatusOK,
+					`{"jsonrpc": "2.0", "result": "`+common.BigToHash(result).Hex()+`"}`,
+				), nil
+			}),
+		)
+
+		tokenRate, err := controller.GetTokenRate()
+
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		assert.Equal(t, "35868e-8", tokenRate.String())
+
+		halfEther := big.NewInt(0).Div(ether, big.NewInt(2))
+
+		accepted, tokens, err := controller.CapByTokensLeft(halfEther, tokenRate)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, halfEther, accepted)
+			assert.Equal(t, "1394000223040000000000", tokens.String())
+		}
 
+		// 1 ether buys exactly the tokens left, which sellTokensForBTCIco rejects.
+		accepted, tokens, err = controller.CapByTokensLeft(ether, tokenRate)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, "999999999999938532", accepted.String())
+			assert.Equal(t, "2788000446079900000000", tokens.String())
+			assert.Equal(t, -1, tokens.Cmp(tokensLeft))
+		}
+
+		tokensLeft.SetInt64(100000000)
+
+		accepted, tokens, err = controller.CapByTokensLeft(ether, tokenRate)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, big.NewInt(0), accepted)
+			assert.Equal(t, big.NewInt(0), tokens)
+		}
+	}
+}
+
+func TestTokenManagementController_IsAdministrator(t *testing.T) {
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
+
+	if assert.NoError(t, err) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		owner := common.HexToAddress("0x123")
+		administrator := common.HexToAddress("0x456")
+		stranger := common.HexToAddress("0x789")
+
+		results := map[string]common.Hash{}
+
+		pack := func(name string, arguments ...interface{}) string {
+			data, err := controller.crowdsaleContractABI.Pack(name, arguments...)
+			assert.NoError(t, err)
+			return hexutil.Bytes(data).String()
+		}
+
+		results[pack("owner")] = owner.Hash()
+		results[pack("isAdministrator", owner)] = common.BigToHash(big.NewInt(0))
+		results[pack("isAdministrator", administrator)] = common.BigToHash(big.NewInt(1))
+		results[pack("isAdministrator", stranger)] = common.BigToHash(big.NewInt(0))
+
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			testRPCEndpoint,
+			batchResponder(func(request *http.Request) (*http.Response, error) {
+				requestBody := new(requestPayload)
+
+				if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+					return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+				}
+
+				result, ok := results[requestBody.Params[0].(map[string]interface{})["data"].(string)]
+
+				if !ok {
+					return nil, errors.New("unexpected call")
+				}
+
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "`+result.Hex()+`"}`), nil
+			}),
+		)
+
+		for account, expected := range map[common.Address]bool{owner: true, administrator: true, stranger: false} {
+			isAdministrator, err := controller.IsAdministrator(account)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, expected, isAdministrator, account.Hex())
+			}
+		}
+	}
+}
+
+func TestTokenManagementController_MintTokens(t *testing.T) {
+	controller, err := MakeTokenManagementController(testInfuraController(), "", "")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	signer := &TransactionSigner{chainID: big.NewInt(5), accountSigner: testKeySigner()}
+
+	*controller = controller.WithTransactionSigner(signer)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	receiver := common.HexToAddress("0x123")
+	results := map[string]common.Hash{}
+
+	pack := func(name string, arguments ...interface{}) string {
+		data, err := controller.crowdsaleContractABI.Pack(name, arguments...)
+		assert.NoError(t, err)
+		return hexutil.Bytes(data).String()
+	}
+
+	for _, name := range []string{"isPreIco", "preIcoTokenRate", "preIcoTokenRateNegativeDecimals", "icoTokenRateNegativeDecimals", "tokensRemainingPreIco"} {
+		results[pack(name)] = common.BigToHash(big.NewInt(0))
+	}
+
+	results[pack("isIco")] = common.BigToHash(big.NewInt(1))
+	results[pack("icoTokenRate")] = common.BigToHash(big.NewInt(1))
+	results[pack("tokensRemainingIco")] = common.BigToHash(big.NewInt(1000000))
+	results[pack("MINIMAL_INVESTMENT")] = common.BigToHash(big.NewInt(100))
+	results[pack("MAXIMAL_INVESTMENT")] = common.BigToHash(big.NewInt(100000))
+	results[pack("owner")] = signer.Address().Hash()
+	results[pack("isAdministrator", signer.Address())] = common.BigToHash(big.NewInt(0))
+	results[pack("isWhitelisted", receiver)] = common.BigToHash(big.NewInt(1))
+	results[pack("paused")] = common.BigToHash(big.NewInt(1))
+
+	simulationResponse := `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "execution reverted"}}`
+	sendResponse := `{"jsonrpc": "2.0", "result": "0x0"}`
+	estimateResponse := `{"jsonrpc": "2.0", "result": "0x30d40"}`
+	sentTransactions := 0
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		batchResponder(func(request *http.Request) (*http.Response, error) {
+			requestBody := new(requestPayload)
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "failure"), nil
+			}
+
+			switch requestBody.Method {
+			case "eth_call":
+				call := requestBody.Params[0].(map[string]interface{})
+
+				if _, ok := call["from"]; ok {
+					return httpmock.NewStringResponse(http.StatusOK, simulationResponse), nil
+				}
+
+				result, ok := results[call["data"].(string)]
+
+				if !ok {
+					return nil, errors.New("unexpected call")
+				}
+
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "`+result.Hex()+`"}`), nil
+			case "eth_estimateGas":
+				call := requestBody.Params[0].(map[string]interface{})
+
+				// The crowdsale only lets the owner and the administrators sell tokens.
+				if call["from"] != strings.ToLower(signer.Address().Hex()) {
+					return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "execution reverted"}}`), nil
+				}
+
+				return httpmock.NewStringResponse(http.StatusOK, estimateResponse), nil
+			case "eth_gasPrice":
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "0x3b9aca00"}`), nil
+			case "eth_getTransactionCount":
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "0x5"}`), nil
+			case "eth_sendRawTransaction":
+				sentTransactions++
+
+				if sendResponse == "" {
+					return httpmock.NewStringResponse(http.StatusGatewayTimeout, ""), nil
+				}
+
+				return httpmock.NewStringResponse(http.StatusOK, sendResponse), nil
+			}
+
+			return nil, errors.New("unexpected method " + requestBody.Method)
+		}),
+	)
+
+	// The crowdsale reverts without a reason, which is inferred from its state.
+	_, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	assert.Equal(t, &RevertError{"crowdsale is paused"}, err)
+
+	results[pack("paused")] = common.BigToHash(big.NewInt(0))
+
+	_, err = controller.MintTokens(receiver, big.NewInt(10))
+
+	assert.Equal(t, &RevertError{ErrBelowMinimalInvestment.Error()}, err)
+
+	simulationResponse = `{"jsonrpc": "2.0", "error": {"code": 3, "message": "execution reverted: out of stock", "data": "` +
+		hexutil.Encode(packRevertData(revertReasonSelector, "string", "out of stock")) + `"}}`
+
+	_, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	assert.Equal(t, &RevertError{"out of stock"}, err)
+	assert.Equal(t, 0, sentTransactions)
+
+	simulationResponse = `{"jsonrpc": "2.0", "result": "0x"}`
+
+	mintTransaction, err := controller.MintTokens(receiver, big.NewInt(1000))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, signer.Address(), mintTransaction.From)
+		assert.Equal(t, uint64(5), mintTransaction.Nonce)
+		assert.Equal(t, uint64(200000), mintTransaction.GasLimit)
+		assert.Equal(t, 1, sentTransactions)
+	}
+
+	// The estimation reverts like the simulation and isn't replaced with a default gas limit.
+	estimateResponse = `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "execution reverted"}}`
+	results[pack("isWhitelisted", receiver)] = common.BigToHash(big.NewInt(0))
+
+	_, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	assert.Equal(t, &RevertError{"wallet is not whitelisted"}, err)
+
+	results[pack("isWhitelisted", receiver)] = common.BigToHash(big.NewInt(1))
+	estimateResponse = `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "header not found"}}`
+
+	_, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	assert.EqualError(t, err, "header not found")
+	assert.Equal(t, 1, sentTransactions)
+
+	estimateResponse = `{"jsonrpc": "2.0", "result": "0x30d40"}`
+
+	// The network asks 1 gwei, more than the ceiling.
+	*controller = controller.WithFeeCeiling(big.NewInt(999999999))
+
+	_, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	assert.Equal(t, ErrFeesAboveCeiling, err)
+	assert.Equal(t, 1, sentTransactions)
+
+	_, err = controller.ForceMintTokens(receiver, big.NewInt(1000))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, sentTransactions)
+	}
+
+	*controller = controller.WithFeeCeiling(nil)
+
+	// The node got the transaction through an earlier attempt.
+	sendResponse = `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "already known"}}`
+
+	mintTransaction, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint64(7), mintTransaction.Nonce)
+	}
+
+	// The endpoint may have accepted the transaction before timing out, so it's tracked and its nonce stays reserved.
+	sendResponse = ""
+
+	mintTransaction, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint64(8), mintTransaction.Nonce)
+	}
+
+	sendResponse = `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "insufficient funds for gas * price + value"}}`
+
+	_, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	assert.EqualError(t, err, "insufficient funds for gas * price + value")
+
+	sendResponse = `{"jsonrpc": "2.0", "result": "0x0"}`
+
+	// The nonce of the rejected transaction is handed out again.
+	mintTransaction, err = controller.MintTokens(receiver, big.NewInt(1000))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint64(9), mintTransaction.Nonce)
+	}
+}
+
+func TestTokenManagementController_AcquireMintSigner(t *testing.T) {
+	infuraController := testInfuraController()
+	controller, err := MakeTokenManagementController(infuraController, "", "")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	signers := testTransactionSigners(2)
+	pool, _ := MakeSignerPool(infuraController, signers, SignerPoolRoundRobin)
+
+	*controller = controller.WithSignerPool(pool)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	estimatedFrom := make([]string, 0)
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		batchResponder(func(request *http.Request) (*http.Response, error) {
+			requestBody := new(requestPayload)
+
+			if err := json.NewDecoder(request.Body).Decode(requestBody); err != nil {
+				return nil, err
+			}
+
+			switch requestBody.Method {
+			case "eth_estimateGas":
+				estimatedFrom = append(estimatedFrom, requestBody.Params[0].(map[string]interface{})["from"].(string))
+
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "0x30d40"}`), nil
+			case "eth_getBalance":
+				// Only the second account can pay for 200000 gas at 1 wei.
+				balance := "0x0"
+
+				if common.HexToAddress(requestBody.Params[0].(string)) == signers[1].Address() {
+					balance = "0x30d40"
+				}
+
+				return httpmock.NewStringResponse(http.StatusOK, `{"jsonrpc": "2.0", "result": "`+balance+`"}`), nil
+			}
+
+			return nil, errors.New("unexpected method " + requestBody.Method)
+		}),
+	)
+
+	fees := &TransactionFees{Type: LegacyTransactionType, GasPrice: big.NewInt(1)}
+
+	// The gas is estimated from the preferred account, then again from the account which can pay for it.
+	signer, gasLimit, err := controller.acquireMintSigner(fees, PhaseICO, common.HexToAddress("0x123"), big.NewInt(1000), []byte{1, 2, 3})
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, signers[1].Address(), signer.Address())
+		assert.Equal(t, uint64(200000), gasLimit)
+		assert.Equal(
+			t,
+			[]string{strings.ToLower(signers[0].Address().Hex()), strings.ToLower(signers[1].Address().Hex())},
+			estimatedFrom,
+		)
+	}
 }
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+)
+
+var tokenRateRegexp = regexp.MustCompile(`^([0-9]+)e-([0-9]+)$`)
+
+// TokenRate mirrors the crowdsale's token rate, i.e. the price of a token in wei is Rate * 10**-NegativeDecimals.
+type TokenRate struct {
+	Rate             *big.Int
+	NegativeDecimals *big.Int
+}
+
+func ParseTokenRate(value string) (*TokenRate, error) {
+	matches := tokenRateRegexp.FindStringSubmatch(value)
+
+	if matches == nil {
+		return nil, errors.New("invalid token rate")
+	}
+
+	rate, _ := new(big.Int).SetString(matches[1], 10)
+	negativeDecimals, _ := new(big.Int).SetString(matches[2], 10)
+
+	if rate.Sign() == 0 {
+		return nil, errors.New("invalid token rate")
+	}
+
+	return &TokenRate{rate, negativeDecimals}, nil
+}
+
+func (tokenRate TokenRate) String() string {
+	return tokenRate.Rate.String() + "e-" + tokenRate.NegativeDecimals.String()
+}
+
+func (tokenRate TokenRate) multiplier() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), tokenRate.NegativeDecimals, nil)
+}
+
+// Tokens computes the amount the crowdsale sells for weiAmount: weiAmount / rate * 10**negativeDecimals.
+func (tokenRate TokenRate) Tokens(weiAmount *big.Int) *big.Int {
+	tokens := new(big.Int).Quo(weiAmount, tokenRate.Rate)
+
+	return tokens.Mul(tokens, tokenRate.multiplier())
+}
+
+// MaxWeiBelow returns the smallest wei amount buying the most tokens that is still below tokensLimit.
+func (tokenRate TokenRate) MaxWeiBelow(tokensLimit *big.Int) *big.Int {
+	if tokensLimit.Sign() != 1 {
+		return big.NewInt(0)
+	}
+
+	units := new(big.Int).Sub(tokensLimit, big.NewInt(1))
+	units.Quo(units, tokenRate.multiplier())
+
+	return units.Mul(units, tokenRate.Rate)
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTokenRate(t *testing.T) {
+	tokenRate, err := ParseTokenRate("17934e-8")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, big.NewInt(17934), tokenRate.Rate)
+		assert.Equal(t, big.NewInt(8), tokenRate.NegativeDecimals)
+		assert.Equal(t, "17934e-8", tokenRate.String())
+	}
+
+	for _, value := range []string{"", "17934", "0e-8", "-1e-8", "1.5e-8"} {
+		_, err = ParseTokenRate(value)
+
+		assert.Error(t, err, value)
+	}
+}
+
+// The expected amounts follow MocrowCoinCrowdsale: _weiAmount.div(tokenRate).mul(10 ** tokenRateNegativeDecimals).
+func TestTokenRate_Tokens(t *testing.T) {
+	ether, _ := new(big.Int).SetString("1000000000000000000", 10)
+
+	preICORate := TokenRate{big.NewInt(17934), big.NewInt(8)}
+	icoRate := TokenRate{big.NewInt(35868), big.NewInt(8)}
+
+	assert.Equal(t, "5576000892160100000000", preICORate.Tokens(ether).String())
+	assert.Equal(t, "2788000446080000000000", icoRate.Tokens(ether).String())
+
+	// Wei below a multiple of the rate buys nothing extra.
+	assert.Equal(t, "100000000", preICORate.Tokens(big.NewInt(17934*2-1)).String())
+	assert.Equal(t, "0", preICORate.Tokens(big.NewInt(17933)).String())
+
+	changedRate := TokenRate{big.NewInt(5), big.NewInt(0)}
+
+	assert.Equal(t, "200000000000000000", changedRate.Tokens(ether).String())
+}
+
+func TestTokenRate_MaxWeiBelow(t *testing.T) {
+	tokenRate := TokenRate{big.NewInt(17934), big.NewInt(8)}
+
+	tokensLeft := big.NewInt(300000001)
+
+	wei := tokenRate.MaxWeiBelow(tokensLeft)
+
+	assert.Equal(t, big.NewInt(17934*3), wei)
+	assert.Equal(t, -1, tokenRate.Tokens(wei).Cmp(tokensLeft))
+
+	tokensLeft = big.NewInt(300000000)
+
+	wei = tokenRate.MaxWeiBelow(tokensLeft)
+
+	assert.Equal(t, big.NewInt(17934*2), wei)
+	assert.Equal(t, -1, tokenRate.Tokens(wei).Cmp(tokensLeft))
+
+	assert.Equal(t, big.NewInt(0), tokenRate.MaxWeiBelow(big.NewInt(0)))
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// TransactionSigner signs every transaction the service sends for the chain of the node, leaving the keys to the account signer.
+type TransactionSigner struct {
+	chainID       *big.Int
+	accountSigner AccountSigner
+}
+
+// MakeTransactionSigner checks that the node is on the expected chain before anything gets signed for it.
+func MakeTransactionSigner(infuraController InfuraController, accountSigner AccountSigner, expectedChainID int64) (*TransactionSigner, error) {
+	if expectedChainID <= 0 {
+		return nil, errors.New("chain id must be positive")
+	}
+
+	response, err := infuraController.callInfura(makeRequestPayload("eth_chainId", []interface{}{}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	chainID, err := hexutil.DecodeBig(response.Result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if chainID.Cmp(big.NewInt(expectedChainID)) != 0 {
+		return nil, fmt.Errorf("node is on chain %s, but chain %d is configured", chainID, expectedChainID)
+	}
+
+	return &TransactionSigner{
+		chainID:       chainID,
+		accountSigner: accountSigner,
+	}, nil
+}
+
+func (transactionSigner TransactionSigner) Address() common.Address {
+	return transactionSigner.accountSigner.Address()
+}
+
+// SignRawTransaction signs the contract call priced with the fees and encodes it for eth_sendRawTransaction.
+func (transactionSigner TransactionSigner) SignRawTransaction(
+	nonce uint64,
+	to common.Address,
+	gasLimit uint64,
+	fees *TransactionFees,
+	data []byte,
+) ([]byte, common.Hash, error) {
+	return transactionSigner.accountSigner.SignTransaction(transactionSigner.chainID, UnsignedTransaction{
+		Nonce: nonce,
+		To:    to,
+		Gas:   gasLimit,
+		Fees:  fees,
+		Data:  data,
+	})
+}
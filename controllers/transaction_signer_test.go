@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func TestMakeTransactionSigner(t *testing.T) {
+	infuraController := testInfuraController()
+	accountSigner := testKeySigner()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(
+		http.MethodPost,
+		testRPCEndpoint,
+		httpmock.NewStringResponder(http.StatusOK, `{"jsonrpc": "2.0", "result": "0x4"}`),
+	)
+
+	transactionSigner, err := MakeTransactionSigner(infuraController, accountSigner, 4)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, big.NewInt(4), transactionSigner.chainID)
+		assert.Equal(t, accountSigner.Address(), transactionSigner.Address())
+	}
+
+	_, err = MakeTransactionSigner(infuraController, accountSigner, 1)
+
+	assert.EqualError(t, err, "node is on chain 4, but chain 1 is configured")
+
+	_, err = MakeTransactionSigner(infuraController, accountSigner, 0)
+
+	assert.Error(t, err)
+}
+
+func TestKeySigner_SignLegacyTransaction(t *testing.T) {
+	signer := testKeySigner()
+
+	transaction := types.NewTransaction(1, common.HexToAddress("0x123"), big.NewInt(0), 21000, big.NewInt(1), nil)
+
+	signedTransaction, err := signer.SignLegacyTransaction(big.NewInt(4), transaction)
+
+	if assert.NoError(t, err) {
+		assert.True(t, signedTransaction.Protected())
+		assert.Equal(t, big.NewInt(4), signedTransaction.ChainId())
+
+		sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(4)), signedTransaction)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, signer.Address(), sender)
+		}
+
+		// The signature isn't valid on other chains.
+		sender, err = types.Sender(types.NewEIP155Signer(big.NewInt(1)), signedTransaction)
+
+		assert.True(t, err != nil || sender != signer.Address())
+	}
+}
@@ -35,19 +35,11 @@ func MakeWhitelistController(
 }
 
 func (controller WhitelistController) IsWhitelisted(address common.Address) (bool, error) {
-	packedData, err := controller.crowdsaleABI.Pack("isWhitelisted", address)
+	var isWhitelisted bool
 
-	if err != nil {
-		return false, err
-	}
-
-	payload := makeCallRequestPayload(controller.crowdsaleAddress, packedData)
-
-	response, err := controller.InfuraController.callInfura(payload)
-
-	if err != nil {
+	if err := controller.InfuraController.retrieveParameter(&isWhitelisted, "isWhitelisted", controller.crowdsaleABI, controller.crowdsaleAddress, address); err != nil {
 		return false, err
 	}
 
-	return common.HexToAddress(response.Result).Big().Int64() == 1, nil
+	return isWhitelisted, nil
 }
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+)
+
+const SatoshisPerBitcoin = 100000000
+
+// Rates are ETH per BTC, so wei = satoshis * rate * weiPerSatoshiAtRateOne.
+var weiPerSatoshiAtRateOne = big.NewInt(10000000000)
+
+// BTCToSatoshis converts a decimal BTC amount, e.g. "0.015", to satoshis.
+func BTCToSatoshis(amount string) (int64, error) {
+	value, ok := new(big.Rat).SetString(amount)
+
+	if !ok || value.Sign() != 1 {
+		return 0, errors.New("invalid bitcoin amount")
+	}
+
+	satoshis := value.Mul(value, new(big.Rat).SetInt64(SatoshisPerBitcoin))
+
+	if !satoshis.IsInt() || !satoshis.Num().IsInt64() {
+		return 0, errors.New("invalid bitcoin amount")
+	}
+
+	return satoshis.Num().Int64(), nil
+}
+
+// RateToRat converts the rate using its shortest decimal representation, so 0.1 is exactly 1/10.
+func RateToRat(rate float64) *big.Rat {
+	value, _ := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+
+	return value
+}
+
+// MulRat returns amount * rate rounded down.
+func MulRat(amount *big.Int, rate *big.Rat) *big.Int {
+	product := new(big.Int).Mul(amount, rate.Num())
+
+	return product.Quo(product, rate.Denom())
+}
+
+// SatoshisToWei converts satoshis to wei at the BTC/ETH rate, rounding down.
+func SatoshisToWei(satoshis int64, rate float64) *big.Int {
+	wei := new(big.Int).Mul(big.NewInt(satoshis), weiPerSatoshiAtRateOne)
+
+	return MulRat(wei, RateToRat(rate))
+}
+
+// WeiToSatoshis converts wei to satoshis at the BTC/ETH rate, rounding down.
+func WeiToSatoshis(wei *big.Int, rate float64) int64 {
+	weiPerSatoshi := new(big.Rat).Mul(RateToRat(rate), new(big.Rat).SetInt(weiPerSatoshiAtRateOne))
+
+	return MulRat(wei, weiPerSatoshi.Inv(weiPerSatoshi)).Int64()
+}
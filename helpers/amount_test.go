@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBTCToSatoshis(t *testing.T) {
+	satoshis, err := BTCToSatoshis("0.015")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1500000), satoshis)
+	}
+
+	satoshis, err = BTCToSatoshis("21")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(2100000000), satoshis)
+	}
+
+	for _, amount := range []string{"", "0", "-1", "0.000000001", "abc"} {
+		_, err = BTCToSatoshis(amount)
+
+		assert.Error(t, err, amount)
+	}
+}
+
+func TestSatoshisToWei(t *testing.T) {
+	// 0.015 BTC at 10 ETH per BTC.
+	assert.Equal(t, "150000000000000000", SatoshisToWei(1500000, 10).String())
+
+	// 0.1 and 0.2 aren't exact in floating point.
+	assert.Equal(t, "100000000000000000", SatoshisToWei(100000000, 0.1).String())
+	assert.Equal(t, "30000000000000000", SatoshisToWei(10000000, 0.3).String())
+
+	assert.Equal(t, "0", SatoshisToWei(0, 10).String())
+}
+
+func TestWeiToSatoshis(t *testing.T) {
+	wei, _ := new(big.Int).SetString("150000000000000000", 10)
+
+	assert.Equal(t, int64(1500000), WeiToSatoshis(wei, 10))
+	assert.Equal(t, int64(100000000), WeiToSatoshis(SatoshisToWei(100000000, 0.1), 0.1))
+
+	// Fractions of a satoshi are rounded down.
+	assert.Equal(t, int64(0), WeiToSatoshis(big.NewInt(99999999999), 10))
+}
+
+func TestMulRat(t *testing.T) {
+	assert.Equal(t, "3", MulRat(big.NewInt(10), big.NewRat(1, 3)).String())
+	assert.Equal(t, "2500", MulRat(big.NewInt(1000), RateToRat(2.5)).String())
+}
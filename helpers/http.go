@@ -18,6 +18,10 @@ func Get(endpoint string, headers Headers) (int, []byte, error) {
 }
 
 func Request(method string, endpoint string, headers Headers, body []byte) (int, []byte, error) {
+	return RequestWithClient(http.DefaultClient, method, endpoint, headers, body)
+}
+
+func RequestWithClient(client *http.Client, method string, endpoint string, headers Headers, body []byte) (int, []byte, error) {
 	request, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
 
 	if err != nil {
@@ -28,7 +32,7 @@ func Request(method string, endpoint string, headers Headers, body []byte) (int,
 		request.Header.Set(header, value)
 	}
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := client.Do(request)
 
 	if err != nil {
 		return 0, nil, err
@@ -0,0 +1,44 @@
+package helpers
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var bitcoinAmountPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,8})?$`)
+
+// MakePaymentURI builds a BIP21 'bitcoin:' URI. Amount is a decimal BTC value and may be empty.
+func MakePaymentURI(address string, amount string, label string) (string, error) {
+	if address == "" {
+		return "", errors.New("empty bitcoin address")
+	}
+
+	parameters := make([]string, 0, 2)
+
+	if amount != "" {
+		if !bitcoinAmountPattern.MatchString(amount) {
+			return "", errors.New("invalid bitcoin amount")
+		}
+
+		parameters = append(parameters, "amount="+amount)
+	}
+
+	if label != "" {
+		parameters = append(parameters, "label="+escapeURIParameter(label))
+	}
+
+	uri := "bitcoin:" + address
+
+	if len(parameters) > 0 {
+		uri += "?" + strings.Join(parameters, "&")
+	}
+
+	return uri, nil
+}
+
+// BIP21 follows RFC 3986, so spaces must not be encoded as '+'.
+func escapeURIParameter(value string) string {
+	return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+}
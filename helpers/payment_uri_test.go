@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakePaymentURI(t *testing.T) {
+	uri, err := MakePaymentURI("mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", "", "")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "bitcoin:mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", uri)
+	}
+
+	uri, err = MakePaymentURI("mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", "0.125", "")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "bitcoin:mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt?amount=0.125", uri)
+	}
+
+	uri, err = MakePaymentURI("mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", "1", "MCW tokens & more")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "bitcoin:mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt?amount=1&label=MCW%20tokens%20%26%20more", uri)
+	}
+}
+
+func TestMakePaymentURI_Fail(t *testing.T) {
+	_, err := MakePaymentURI("", "1", "")
+
+	assert.Error(t, err)
+
+	_, err = MakePaymentURI("mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", "0.123456789", "")
+
+	assert.Error(t, err)
+
+	_, err = MakePaymentURI("mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", "-1", "")
+
+	assert.Error(t, err)
+
+	_, err = MakePaymentURI("mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", "1e3", "")
+
+	assert.Error(t, err)
+}
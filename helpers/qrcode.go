@@ -0,0 +1,44 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/skip2/go-qrcode"
+)
+
+func EncodeQRCodePNG(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
+
+func EncodeQRCodeSVG(content string, size int) ([]byte, error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := code.Bitmap()
+	modules := len(bitmap)
+
+	buffer := new(bytes.Buffer)
+
+	fmt.Fprintf(
+		buffer,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules,
+	)
+	fmt.Fprintf(buffer, `<rect width="%d" height="%d" fill="#ffffff"/>`, modules, modules)
+
+	for y, row := range bitmap {
+		for x, isDark := range row {
+			if isDark {
+				fmt.Fprintf(buffer, `<rect x="%d" y="%d" width="1" height="1" fill="#000000"/>`, x, y)
+			}
+		}
+	}
+
+	buffer.WriteString("</svg>")
+
+	return buffer.Bytes(), nil
+}
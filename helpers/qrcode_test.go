@@ -0,0 +1,35 @@
+package helpers
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeQRCodePNG(t *testing.T) {
+	data, err := EncodeQRCodePNG("bitcoin:mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", 256)
+
+	if assert.NoError(t, err) {
+		image, err := png.Decode(bytes.NewReader(data))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, 256, image.Bounds().Dx())
+			assert.Equal(t, 256, image.Bounds().Dy())
+		}
+	}
+}
+
+func TestEncodeQRCodeSVG(t *testing.T) {
+	data, err := EncodeQRCodeSVG("bitcoin:mkHS9ne12qx9pS9VojpwU5xtRd4T7X7ZUt", 256)
+
+	if assert.NoError(t, err) {
+		svg := string(data)
+
+		assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256"`))
+		assert.True(t, strings.HasSuffix(svg, "</svg>"))
+		assert.Contains(t, svg, `fill="#000000"`)
+	}
+}
@@ -3,8 +3,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 
+	"MCW-btc-module/controllers"
 	"MCW-btc-module/model"
 	"MCW-btc-module/server"
 
@@ -15,6 +18,10 @@ import (
 )
 
 func main() {
+	checkAdministrators := flag.Bool("check-administrators", false, "check that the crowdsale lets the signing accounts sell tokens and exit")
+	forceMint := flag.Uint("force-mint", 0, "mint the purchase with the id waiting for gas whatever the network fees are")
+	flag.Parse()
+
 	fmt.Println("GET CONFIG")
 
 	config.AddConfigPath(".")
@@ -23,6 +30,16 @@ func main() {
 	if err := config.ReadInConfig(); err != nil {
 		panic(fmt.Errorf("Fatal error getting config from file: %s \n", err))
 	}
+
+	if *checkAdministrators {
+		if err := server.CheckAdministrators(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	if config.GetBool("daemon.enabled") {
 
 		cntxt := &daemon.Context{
@@ -62,8 +79,19 @@ func main() {
 		panic("COULDN'T CONNECT TO DATABASE " + err.Error())
 	}
 
+	if *forceMint != 0 {
+		if err := controllers.ForceMint(database, *forceMint); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	fmt.Println("BEGIN MIGRATIONS")
-	database.AutoMigrate(&model.BTCTransaction{})
+	if err := model.Migrate(database); err != nil {
+		panic("COULDN'T MIGRATE DATABASE " + err.Error())
+	}
 	fmt.Println("END MIGRATIONS")
 
 	server, err := server.New(database)
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// AccountNonce is the next nonce the service hands out for the signing account.
+type AccountNonce struct {
+	Address   string    `gorm:"primary_key" json:"address"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	NextNonce uint64    `json:"nextNonce"`
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// BigInt is stored in numeric columns, e.g. wei and token amounts, and is encoded to JSON as a decimal string.
+type BigInt struct {
+	big.Int
+}
+
+// NewBigInt returns nil for nil values, which are stored as NULL.
+func NewBigInt(value *big.Int) *BigInt {
+	if value == nil {
+		return nil
+	}
+
+	result := new(BigInt)
+	result.Set(value)
+
+	return result
+}
+
+// Big returns a copy of the value, or nil for nil values.
+func (value *BigInt) Big() *big.Int {
+	if value == nil {
+		return nil
+	}
+
+	return new(big.Int).Set(&value.Int)
+}
+
+func (value BigInt) Value() (driver.Value, error) {
+	return value.String(), nil
+}
+
+func (value *BigInt) Scan(source interface{}) error {
+	var text string
+
+	switch source := source.(type) {
+	case []byte:
+		text = string(source)
+	case string:
+		text = source
+	case int64:
+		value.SetInt64(source)
+		return nil
+	default:
+		return fmt.Errorf("can't scan %T into BigInt", source)
+	}
+
+	if _, ok := value.SetString(text, 10); !ok {
+		return errors.New("invalid numeric value " + text)
+	}
+
+	return nil
+}
+
+func (value BigInt) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + value.String() + `"`), nil
+}
+
+func (value *BigInt) UnmarshalJSON(data []byte) error {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return errors.New("BigInt must be encoded as string")
+	}
+
+	return value.Scan(data[1 : len(data)-1])
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBigInt_Scan(t *testing.T) {
+	wei, _ := new(big.Int).SetString("1234567890123456789012345678901234567890", 10)
+
+	value := NewBigInt(wei)
+
+	stored, err := value.Value()
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1234567890123456789012345678901234567890", stored)
+	}
+
+	scanned := new(BigInt)
+
+	if assert.NoError(t, scanned.Scan([]byte("1234567890123456789012345678901234567890"))) {
+		assert.Equal(t, 0, scanned.Cmp(wei))
+	}
+
+	if assert.NoError(t, scanned.Scan(int64(42))) {
+		assert.Equal(t, int64(42), scanned.Int64())
+	}
+
+	assert.Error(t, scanned.Scan("1.5"))
+	assert.Error(t, scanned.Scan(1.5))
+}
+
+func TestBigInt_JSON(t *testing.T) {
+	transaction := BTCTransaction{TokensAmount: NewBigInt(big.NewInt(1500))}
+
+	encoded, err := json.Marshal(transaction)
+
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(encoded), `"tokensAmount":"1500"`)
+		assert.Contains(t, string(encoded), `"acceptedWei":null`)
+	}
+
+	decoded := new(BTCTransaction)
+
+	if assert.NoError(t, json.Unmarshal(encoded, decoded)) {
+		assert.Equal(t, int64(1500), decoded.TokensAmount.Int64())
+		assert.Nil(t, decoded.AcceptedWei)
+	}
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// renamedAmountColumns maps the BTC amount columns of purchases recorded in BTC to the ones in satoshis replacing them.
+var renamedAmountColumns = map[string]string{
+	"amount_transferred": "satoshis_transferred",
+	"amount_refunded":    "satoshis_refunded",
+}
+
+// Migrate creates and updates the tables, then moves the data of renamed columns.
+func Migrate(database *gorm.DB) error {
+	err := database.AutoMigrate(&BTCTransaction{}, &RateHistory{}, &AccountNonce{}, &MintReplacement{}).Error
+
+	if err != nil {
+		return err
+	}
+
+	return migrateSatoshis(database)
+}
+
+// migrateSatoshis copies the BTC amounts of purchases recorded before amounts were stored in satoshis. The old columns
+// are kept, and purchases which already have an amount in satoshis are skipped, so the migration can run on every start.
+func migrateSatoshis(database *gorm.DB) error {
+	table := database.NewScope(&BTCTransaction{}).TableName()
+
+	for oldColumn, newColumn := range renamedAmountColumns {
+		if !database.Dialect().HasColumn(table, oldColumn) {
+			continue
+		}
+
+		err := database.Exec(fmt.Sprintf(
+			"UPDATE %s SET %s = round(%s * 100000000) WHERE (%s IS NULL OR %s = 0) AND %s IS NOT NULL AND %s <> 0",
+			table, newColumn, oldColumn, newColumn, newColumn, oldColumn, oldColumn,
+		)).Error
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "test.db")
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	defer db.Close()
+
+	db.DropTableIfExists(BTCTransaction{})
+
+	// The table as it was when amounts were recorded in BTC.
+	db.Exec("CREATE TABLE btc_transactions (id integer primary key autoincrement, amount_transferred real, amount_refunded real, status integer)")
+	db.Exec("INSERT INTO btc_transactions (amount_transferred, amount_refunded, status) VALUES (0.015, 0.0003, 1), (0.29, 0, 1), (NULL, NULL, 0)")
+
+	if !assert.NoError(t, Migrate(db)) {
+		t.FailNow()
+	}
+
+	transactions := make([]BTCTransaction, 0)
+
+	if assert.NoError(t, db.Order("id").Find(&transactions).Error) && assert.Len(t, transactions, 3) {
+		assert.Equal(t, int64(1500000), transactions[0].SatoshisTransferred)
+		assert.Equal(t, int64(30000), transactions[0].SatoshisRefunded)
+		// 0.29 BTC isn't exact as a float.
+		assert.Equal(t, int64(29000000), transactions[1].SatoshisTransferred)
+		assert.Equal(t, int64(0), transactions[1].SatoshisRefunded)
+		assert.Equal(t, int64(0), transactions[2].SatoshisTransferred)
+	}
+
+	// Amounts recorded in satoshis since are kept.
+	db.Model(&transactions[1]).Update("satoshis_transferred", 100)
+
+	if assert.NoError(t, Migrate(db)) && assert.NoError(t, db.First(&transactions[1], transactions[1].ID).Error) {
+		assert.Equal(t, int64(100), transactions[1].SatoshisTransferred)
+	}
+
+	db.DropTableIfExists(BTCTransaction{})
+}
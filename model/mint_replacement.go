@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// MintReplacement records the mint transaction which replaced a stuck one of the purchase.
+type MintReplacement struct {
+	ID                   uint      `gorm:"primary_key" json:"id"`
+	CreatedAt            time.Time `json:"createdAt"`
+	TransactionID        uint      `gorm:"index" json:"transactionId"`
+	ReplacedHash         string    `json:"replacedHash"`
+	Hash                 string    `json:"hash"`
+	GasPrice             *BigInt   `gorm:"type:numeric(78,0)" json:"gasPrice"`
+	MaxFeePerGas         *BigInt   `gorm:"type:numeric(78,0)" json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *BigInt   `gorm:"type:numeric(78,0)" json:"maxPriorityFeePerGas"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type RateHistory struct {
+	ID            uint      `gorm:"primary_key" json:"id"`
+	CreatedAt     time.Time `gorm:"index" json:"createdAt"`
+	TransactionID uint      `gorm:"index" json:"transactionId"`
+	Kind          string    `json:"kind"`
+	ExchangeRate  string    `json:"exchangeRate"`
+	TokenRate     string    `json:"tokenRate"`
+}
+
+const RATE_HISTORY_KIND_QUOTE = "quote"
+const RATE_HISTORY_KIND_PURCHASE = "purchase"
@@ -1,15 +1,52 @@
 package model
 
+import "time"
+
 type BTCTransaction struct {
-	ID                uint    `gorm:"primary_key" json:"id"`
-	EthereumAddress   string  `json:"ethereumAddress"`
-	BitcoinAddress    string  `json:"bitcoinAddress"`
-	AmountTransferred float64 `json:"amountTransferred"`
-	Index             uint32  `json:"depth"`
-	Error             string  `json:"error"`
-	Status            int8    `json:"status"`
+	ID                       uint       `gorm:"primary_key" json:"id"`
+	EthereumAddress          string     `json:"ethereumAddress"`
+	BitcoinAddress           string     `json:"bitcoinAddress"`
+	PaymentHash              string     `json:"paymentHash"`
+	PaymentRequest           string     `json:"paymentRequest"`
+	SatoshisTransferred      int64      `json:"satoshisTransferred"`
+	SatoshisRefunded         int64      `json:"satoshisRefunded"`
+	QuoteRate                float64    `json:"quoteRate"`
+	QuoteTokenRate           string     `json:"quoteTokenRate"`
+	QuotePhase               string     `json:"quotePhase"`
+	QuoteExpiresAt           *time.Time `json:"quoteExpiresAt"`
+	QuoteSignature           string     `json:"quoteSignature"`
+	ExchangeRate             string     `json:"exchangeRate"`
+	AcceptedWei              *BigInt    `gorm:"type:numeric(78,0)" json:"acceptedWei"`
+	RefundedWei              *BigInt    `gorm:"type:numeric(78,0)" json:"refundedWei"`
+	TokenRate                string     `json:"tokenRate"`
+	TokensAmount             *BigInt    `gorm:"type:numeric(78,0)" json:"tokensAmount"`
+	MintTransactionType      int8       `json:"mintTransactionType"`
+	MintGasLimit             uint64     `json:"mintGasLimit"`
+	MintGasPrice             *BigInt    `gorm:"type:numeric(78,0)" json:"mintGasPrice"`
+	MintMaxFeePerGas         *BigInt    `gorm:"type:numeric(78,0)" json:"mintMaxFeePerGas"`
+	MintMaxPriorityFeePerGas *BigInt    `gorm:"type:numeric(78,0)" json:"mintMaxPriorityFeePerGas"`
+	MintFrom                 string     `json:"mintFrom"`
+	MintTransactionHash      string     `json:"mintTransactionHash"`
+	MintNonce                uint64     `json:"mintNonce"`
+	MintData                 string     `json:"mintData"`
+	MintSentAt               *time.Time `json:"mintSentAt"`
+	MintBlockNumber          uint64     `json:"mintBlockNumber"`
+	MintGasUsed              uint64     `json:"mintGasUsed"`
+	MintRevertReason         string     `json:"mintRevertReason"`
+	MintIsForced             bool       `json:"mintIsForced"`
+	Index                    uint32     `json:"depth"`
+	Error                    string     `json:"error"`
+	Status                   int8       `json:"status"`
 }
 
 const TRANSACTION_STATUS_ERROR = -1
 const TRANSACTON_STATUS_NEW = 0
 const TRANSACTION_STATUS_SUCCESS = 1
+const TRANSACTION_STATUS_BELOW_MINIMUM = 2
+const TRANSACTION_STATUS_REFUND = 3
+const TRANSACTION_STATUS_QUOTE_EXPIRED = 4
+const TRANSACTION_STATUS_NEEDS_REVIEW = 5
+const TRANSACTION_STATUS_MINTING = 6
+const TRANSACTION_STATUS_MINT_FAILED = 7
+const TRANSACTION_STATUS_WAITING_FOR_GAS = 8
+const TRANSACTION_STATUS_WAITING_FOR_BALANCE = 9
@@ -2,8 +2,11 @@ package routing
 
 import (
 	"errors"
+	"strconv"
+	"time"
 
 	"MCW-btc-module/controllers"
+	"MCW-btc-module/model"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/labstack/echo"
@@ -14,17 +17,34 @@ import (
 type ExchangeRouter struct {
 	*controllers.ExchangeController
 	*controllers.WhitelistController
+	controllers.PaymentRequestController
+	lightningExchangeController *controllers.LightningExchangeController
 }
 
-func MakeExchangeRouter(exchangeController *controllers.ExchangeController, whitelistController *controllers.WhitelistController) ExchangeRouter {
+func MakeExchangeRouter(
+	exchangeController *controllers.ExchangeController,
+	whitelistController *controllers.WhitelistController,
+	paymentRequestController controllers.PaymentRequestController,
+	lightningExchangeController *controllers.LightningExchangeController,
+) ExchangeRouter {
 	return ExchangeRouter{
-		ExchangeController:  exchangeController,
-		WhitelistController: whitelistController,
+		ExchangeController:          exchangeController,
+		WhitelistController:         whitelistController,
+		PaymentRequestController:    paymentRequestController,
+		lightningExchangeController: lightningExchangeController,
 	}
 }
 
 func (router ExchangeRouter) Register(group *echo.Group) {
 	group.GET("/:address", router.buyTokens)
+	group.GET("/rates", router.getRateHistory)
+	group.GET("/quote", router.previewPurchase)
+	group.GET("/payment/:bitcoinAddress", router.getPaymentRequest)
+	group.GET("/payment/:bitcoinAddress/qrcode", router.getPaymentQRCode)
+
+	if router.lightningExchangeController != nil {
+		group.GET("/lightning/:address", router.buyTokensWithLightning)
+	}
 }
 
 func (router ExchangeRouter) buyTokens(context echo.Context) error {
@@ -59,7 +79,143 @@ func (router ExchangeRouter) buyTokens(context echo.Context) error {
 
 	return context.JSON(http.StatusOK, map[string]interface{}{
 		"address": transaction.BitcoinAddress,
-		"isNew": isNew,
+		"isNew":   isNew,
+		"quote":   quoteResponse(transaction),
+	})
+}
+
+func (router ExchangeRouter) buyTokensWithLightning(context echo.Context) error {
+	address := common.HexToAddress(context.Param("address"))
+
+	if address == common.HexToAddress("") {
+		return errors.New("invalid address")
+	}
+
+	whitelisted, err := router.IsWhitelisted(address)
+
+	if err != nil {
+		return err
+	}
+
+	if !whitelisted {
+		return errors.New("address is not whitelisted")
+	}
+
+	transaction, err := router.lightningExchangeController.CreateInvoiceEntry(address.String(), context.QueryParam("amount"))
+
+	if err != nil {
+		return err
+	}
+
+	go router.lightningExchangeController.BuyTokensWithInvoice(transaction)
+
+	return context.JSON(http.StatusOK, map[string]interface{}{
+		"paymentRequest": transaction.PaymentRequest,
+		"paymentHash":    transaction.PaymentHash,
+		"quote":          quoteResponse(transaction),
+	})
+}
+
+func quoteResponse(transaction *model.BTCTransaction) map[string]interface{} {
+	return map[string]interface{}{
+		"rate":      transaction.QuoteRate,
+		"tokenRate": transaction.QuoteTokenRate,
+		"phase":     transaction.QuotePhase,
+		"expiresAt": transaction.QuoteExpiresAt,
+		"signature": transaction.QuoteSignature,
+	}
+}
+
+func (router ExchangeRouter) getPaymentRequest(context echo.Context) error {
+	transaction, err := router.ExchangeController.GetTransactionByBitcoinAddress(context.Param("bitcoinAddress"))
+
+	if err != nil {
+		return err
+	}
+
+	uri, err := router.PaymentRequestController.GetPaymentURI(transaction, context.QueryParam("amount"))
+
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusOK, map[string]interface{}{
+		"address": transaction.BitcoinAddress,
+		"uri":     uri,
+	})
+}
+
+func (router ExchangeRouter) getPaymentQRCode(context echo.Context) error {
+	transaction, err := router.ExchangeController.GetTransactionByBitcoinAddress(context.Param("bitcoinAddress"))
+
+	if err != nil {
+		return err
+	}
+
+	image, contentType, err := router.PaymentRequestController.GetPaymentQRCode(
+		transaction,
+		context.QueryParam("amount"),
+		context.QueryParam("format"),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return context.Blob(http.StatusOK, contentType, image)
+}
+
+func (router ExchangeRouter) getRateHistory(context echo.Context) error {
+	var from, to time.Time
+	var limit int
+	var err error
+
+	if value := context.QueryParam("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return errors.New("invalid 'from' time")
+		}
+	}
+
+	if value := context.QueryParam("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return errors.New("invalid 'to' time")
+		}
+	}
+
+	if value := context.QueryParam("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			return errors.New("invalid limit")
+		}
+	}
+
+	records, err := router.ExchangeController.GetRateHistory(from, to, limit)
+
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusOK, map[string]interface{}{
+		"rates": records,
 	})
 }
 
+func (router ExchangeRouter) previewPurchase(context echo.Context) error {
+	var investor *common.Address
+
+	if value := context.QueryParam("address"); value != "" {
+		if !common.IsHexAddress(value) {
+			return errors.New("invalid address")
+		}
+
+		address := common.HexToAddress(value)
+		investor = &address
+	}
+
+	preview, err := router.ExchangeController.PreviewPurchase(context.QueryParam("amount"), investor)
+
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusOK, preview)
+}
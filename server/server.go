@@ -1,6 +1,11 @@
 package server
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
 	"MCW-btc-module/controllers"
 	"MCW-btc-module/routing"
 
@@ -51,16 +56,130 @@ func New(database *gorm.DB) (*Server, error) {
 		blocktrailController,
 	)
 
-	infuraController := controllers.MakeInfuraController(
-		config.GetString("infura.accessToken"),
-		config.GetBool("infura.isTestnet"),
-	)
+	infuraController, err := makeInfuraController()
+
+	if err != nil {
+		return nil, err
+	}
 
 	tokenManagementController, err := controllers.MakeTokenManagementController(
 		infuraController,
 		config.GetString("crowdsale.address"),
 		config.GetString("crowdsale.ownerAddress"),
-		config.GetString("crowdsale.ownerPrivateKey"),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signerPool, err := makeSignerPool(infuraController)
+
+	if err != nil {
+		return nil, err
+	}
+
+	feeController, err := controllers.MakeFeeController(
+		infuraController,
+		config.GetBool("fees.isDynamicFeeEnabled"),
+		gweiToWei(config.GetInt64("fees.maxFeePerGasGwei")),
+		gweiToWei(config.GetInt64("fees.maxPriorityFeePerGasGwei")),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	*tokenManagementController = tokenManagementController.
+		WithTransactionSigner(signerPool.Signers()[0]).
+		WithSignerPool(signerPool).
+		WithFeeCeiling(gweiToWei(config.GetInt64("mint.maxNetworkFeePerGasGwei"))).
+		WithFeeController(feeController).
+		WithNonceManager(controllers.MakeNonceManager(infuraController, database))
+
+	rateSources := make([]controllers.RateSource, 0)
+
+	for name, endpoint := range config.GetStringMapString("rates.sources") {
+		rateSource, err := controllers.MakeRateSource(name, endpoint)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rateSources = append(rateSources, rateSource)
+	}
+
+	var priceFeedRateSource *controllers.PriceFeedRateSource
+
+	if config.GetString("rates.priceFeed.address") != "" {
+		priceFeedRateSource, err = controllers.MakePriceFeedRateSource(
+			infuraController,
+			config.GetString("rates.priceFeed.address"),
+			config.GetDuration("rates.priceFeed.maxAge"),
+			uint8(config.GetInt("rates.priceFeed.decimals")),
+			config.GetBool("rates.priceFeed.isInverted"),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !config.GetBool("rates.priceFeed.isReference") {
+			rateSources = append(rateSources, priceFeedRateSource)
+		}
+	}
+
+	rateOracleController, err := controllers.MakeRateOracleController(
+		rateSources,
+		config.GetFloat64("rates.maxDeviation"),
+		config.GetInt("rates.minSources"),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if priceFeedRateSource != nil && config.GetBool("rates.priceFeed.isReference") {
+		rateOracleController = rateOracleController.WithReference(
+			priceFeedRateSource,
+			config.GetFloat64("rates.priceFeed.maxDeviation"),
+		)
+	}
+
+	quoteController, err := controllers.MakeQuoteController(
+		config.GetString("quotes.secret"),
+		config.GetDuration("quotes.validity"),
+		config.GetString("quotes.expiredPolicy"),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rateCircuitBreakerController, err := controllers.MakeRateCircuitBreakerController(
+		config.GetFloat64("rates.circuitBreaker.maxLastRateDeviation"),
+		config.GetFloat64("rates.circuitBreaker.maxMedianDeviation"),
+		config.GetDuration("rates.circuitBreaker.maxRateAge"),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	receiptController, err := controllers.MakeReceiptController(
+		infuraController,
+		uint64(config.GetInt64("mint.confirmations")),
+		config.GetDuration("mint.receiptPollInterval"),
+		config.GetDuration("mint.receiptTimeout"),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	gasBumpingController, err := controllers.MakeGasBumpingController(
+		config.GetDuration("mint.bumpAfter"),
+		config.GetInt64("mint.feeBumpPercent"),
+		gweiToWei(config.GetInt64("mint.maxBumpedFeePerGasGwei")),
 	)
 
 	if err != nil {
@@ -70,6 +189,11 @@ func New(database *gorm.DB) (*Server, error) {
 	exchangeController, err := controllers.MakeExchangeController(
 		monitoringController,
 		*tokenManagementController,
+		rateOracleController,
+		quoteController,
+		rateCircuitBreakerController,
+		receiptController,
+		gasBumpingController,
 		database,
 		config.GetString("bitcoin.xPub"),
 		config.GetBool("bitcoin.isTestnet"),
@@ -79,11 +203,205 @@ func New(database *gorm.DB) (*Server, error) {
 		return nil, err
 	}
 
+	if pollInterval := config.GetDuration("mint.waitingPollInterval"); pollInterval > 0 {
+		go exchangeController.MintWaitingPurchases(pollInterval)
+	}
+
+	if checkInterval := config.GetDuration("alerts.balanceCheckInterval"); checkInterval > 0 {
+		notifier, err := controllers.MakeNotifier(
+			config.GetString("alerts.notifier"),
+			config.GetString("alerts.webhookUrl"),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		balanceMonitorController, err := controllers.MakeBalanceMonitorController(
+			signerPool,
+			feeController,
+			notifier,
+			database,
+			uint64(config.GetInt64("alerts.mintGasLimit")),
+			config.GetInt64("alerts.reserveMints"),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		go balanceMonitorController.Monitor(checkInterval)
+	}
+
 	whitelistController, err := controllers.MakeWhitelistController(infuraController, config.GetString("crowdsale.address"))
 
-	exchangeRouter := routing.MakeExchangeRouter(exchangeController, whitelistController)
+	paymentRequestController := controllers.MakePaymentRequestController(
+		config.GetString("bitcoin.paymentLabel"),
+		config.GetInt("bitcoin.qrCodeSize"),
+	)
+
+	var lightningExchangeController *controllers.LightningExchangeController
+
+	if config.GetBool("lightning.enabled") {
+		lightningController, err := controllers.MakeLightningController(
+			config.GetString("lightning.endpoint"),
+			config.GetString("lightning.macaroonPath"),
+			config.GetString("lightning.tlsCertificatePath"),
+			config.GetDuration("lightning.invoiceExpiry"),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		lightningExchangeController = controllers.MakeLightningExchangeController(
+			exchangeController,
+			lightningController,
+			config.GetString("lightning.invoiceMemo"),
+		)
+	}
+
+	// The purchases which were pending when the service stopped are watched again.
+	exchangeController.ResumeMonitoring()
+
+	if lightningExchangeController != nil {
+		lightningExchangeController.ResumeMonitoring()
+	}
+
+	exchangeRouter := routing.MakeExchangeRouter(
+		exchangeController,
+		whitelistController,
+		paymentRequestController,
+		lightningExchangeController,
+	)
 
 	exchangeRouter.Register(mainGroup)
 
 	return &server, nil
 }
+
+func makeInfuraController() (controllers.InfuraController, error) {
+	rpcEndpoints := make([]controllers.RPCEndpoint, 0)
+
+	if err := config.UnmarshalKey("ethereum.endpoints", &rpcEndpoints); err != nil {
+		return controllers.InfuraController{}, err
+	}
+
+	if len(rpcEndpoints) == 0 && config.GetString("infura.accessToken") != "" {
+		rpcEndpoints = append(rpcEndpoints, controllers.MakeInfuraEndpoint(
+			config.GetString("infura.accessToken"),
+			config.GetBool("infura.isTestnet"),
+		))
+	}
+
+	return controllers.MakeRPCController(rpcEndpoints)
+}
+
+// makeSignerPool pools the signer of the owner, which comes first, with the signers in 'crowdsale.administrators'.
+func makeSignerPool(infuraController controllers.InfuraController) (*controllers.SignerPool, error) {
+	var ownerSignerConfig controllers.AccountSignerConfig
+
+	if err := config.UnmarshalKey("crowdsale.signer", &ownerSignerConfig); err != nil {
+		return nil, err
+	}
+
+	if ownerSignerConfig.Type == "" {
+		ownerSignerConfig.Type = controllers.AccountSignerKey
+		ownerSignerConfig.PrivateKey = config.GetString("crowdsale.ownerPrivateKey")
+	}
+
+	if ownerSignerConfig.Address == "" {
+		ownerSignerConfig.Address = config.GetString("crowdsale.ownerAddress")
+	}
+
+	administratorSignerConfigs := make([]controllers.AccountSignerConfig, 0)
+
+	if err := config.UnmarshalKey("crowdsale.administrators", &administratorSignerConfigs); err != nil {
+		return nil, err
+	}
+
+	signers := make([]*controllers.TransactionSigner, 0)
+
+	for _, signerConfig := range append([]controllers.AccountSignerConfig{ownerSignerConfig}, administratorSignerConfigs...) {
+		accountSigner, err := controllers.MakeAccountSigner(signerConfig)
+
+		if err != nil {
+			return nil, err
+		}
+
+		transactionSigner, err := controllers.MakeTransactionSigner(
+			infuraController,
+			accountSigner,
+			config.GetInt64("ethereum.chainId"),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		signers = append(signers, transactionSigner)
+	}
+
+	strategy := config.GetString("crowdsale.signerStrategy")
+
+	if strategy == "" {
+		strategy = controllers.SignerPoolRoundRobin
+	}
+
+	return controllers.MakeSignerPool(infuraController, signers, strategy)
+}
+
+// CheckAdministrators prints whether the crowdsale lets each signing account sell tokens and the balance of the account.
+func CheckAdministrators(output io.Writer) error {
+	infuraController, err := makeInfuraController()
+
+	if err != nil {
+		return err
+	}
+
+	tokenManagementController, err := controllers.MakeTokenManagementController(
+		infuraController,
+		config.GetString("crowdsale.address"),
+		config.GetString("crowdsale.ownerAddress"),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	signerPool, err := makeSignerPool(infuraController)
+
+	if err != nil {
+		return err
+	}
+
+	isEverySignerAllowed := true
+
+	for _, signer := range signerPool.Signers() {
+		isAdministrator, err := tokenManagementController.IsAdministrator(signer.Address())
+
+		if err != nil {
+			return err
+		}
+
+		balance, err := signerPool.GetBalance(signer.Address())
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(output, "%s administrator: %t balance: %s wei\n", signer.Address().Hex(), isAdministrator, balance)
+
+		isEverySignerAllowed = isEverySignerAllowed && isAdministrator
+	}
+
+	if !isEverySignerAllowed {
+		return errors.New("some signing accounts are neither the owner nor administrators of the crowdsale")
+	}
+
+	return nil
+}
+
+func gweiToWei(gwei int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(gwei), big.NewInt(1000000000))
+}